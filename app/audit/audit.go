@@ -0,0 +1,109 @@
+// Package audit records the network endpoints a supervised process touched,
+// e.g. for compliance and egress reviews. Entries are written as JSON lines.
+package audit
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Decisions of entries.
+const (
+	Allowed = "allowed"
+	Denied  = "denied"
+	// Logged is the decision for operations that were not allowed by the
+	// policy, but let through because it only logs them.
+	Logged = "logged"
+)
+
+// Entry describes an operation of the supervised process on a network
+// endpoint.
+type Entry struct {
+	Time time.Time `json:"time"`
+	PID  int       `json:"pid,omitempty"`
+	// Exe is the executable of the process.
+	Exe string `json:"exe,omitempty"`
+	// Event is the kind of operation, e.g. "connect", "bind", "accept",
+	// "send", "dns", "host" or "proxy".
+	Event string `json:"event"`
+	// Syscall is the syscall of the operation, if any.
+	Syscall string `json:"syscall,omitempty"`
+	// Family is the address family, e.g. "inet6" or "unix".
+	Family string `json:"family,omitempty"`
+	// Protocol is the protocol of the socket, e.g. "tcp" or "udp".
+	Protocol string `json:"protocol,omitempty"`
+	Local    string `json:"local,omitempty"`
+	Remote   string `json:"remote,omitempty"`
+	// Host is the name a DNS query, TLS ClientHello, HTTP request or
+	// proxy request is for.
+	Host     string `json:"host,omitempty"`
+	Decision string `json:"decision"`
+}
+
+// maxSeen bounds the keys Once remembers, so that e.g. a scan of many
+// destinations cannot grow the memory of the gatekeeper without limit.
+const maxSeen = 4096
+
+// Log writes entries to an audit stream. A nil Log discards entries, so
+// callers do not need to check whether auditing is enabled. A Log is safe for
+// concurrent use.
+type Log struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	// seen holds the keys of Once, the most recently used first.
+	seen     *list.List
+	seenKeys map[string]*list.Element
+}
+
+// New returns a Log writing to w.
+func New(w io.Writer) *Log {
+	return &Log{enc: json.NewEncoder(w), seen: list.New(), seenKeys: make(map[string]*list.Element)}
+}
+
+// Record writes e. The time of the entry is set if it is zero.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(e)
+}
+
+// Once writes the entry returned by entry unless an entry was recorded with
+// the same key before, e.g. to record only the first message to each
+// destination. entry is only called for a new key, so that an entry that is
+// expensive to build is not built for every message. Only the maxSeen most
+// recently used keys are remembered, so an entry whose key was not used for
+// long may be recorded again.
+func (l *Log) Once(key string, entry func() Entry) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.seenKeys[key]; ok {
+		l.seen.MoveToFront(el)
+		return
+	}
+	l.seenKeys[key] = l.seen.PushFront(key)
+	if l.seen.Len() > maxSeen {
+		oldest := l.seen.Back()
+		l.seen.Remove(oldest)
+		delete(l.seenKeys, oldest.Value.(string))
+	}
+	l.write(entry())
+}
+
+func (l *Log) write(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := l.enc.Encode(e); err != nil {
+		fmt.Printf("Error writing the audit log: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b)
+	l.Record(Entry{PID: 42, Event: "connect", Family: "inet", Protocol: "tcp", Remote: "192.0.2.1:443", Decision: Allowed})
+	send := func() Entry { return Entry{PID: 42, Event: "send", Remote: "192.0.2.1:53", Decision: Allowed} }
+	l.Once("42 192.0.2.1:53", send)
+	l.Once("42 192.0.2.1:53", func() Entry {
+		t.Fatal("expected the entry of a remembered key not to be built")
+		return Entry{}
+	})
+
+	var entries []Entry
+	s := bufio.NewScanner(&b)
+	for s.Scan() {
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("expected JSON lines, got %q: %v", s.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Event != "connect" || e.Remote != "192.0.2.1:443" || e.Time.IsZero() {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
+
+func TestOnceIsBounded(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b)
+	send := func() Entry { return Entry{Event: "send"} }
+	for i := 0; i <= maxSeen; i++ {
+		l.Once(fmt.Sprint(i), send)
+	}
+	if len(l.seenKeys) != maxSeen || l.seen.Len() != maxSeen {
+		t.Fatalf("expected %d remembered keys, got %d", maxSeen, len(l.seenKeys))
+	}
+
+	// The least recently used key was forgotten, the others are not
+	// recorded again.
+	b.Reset()
+	l.Once(fmt.Sprint(maxSeen), send)
+	if b.Len() != 0 {
+		t.Fatalf("expected a remembered key not to be recorded again")
+	}
+	l.Once("0", send)
+	if b.Len() == 0 {
+		t.Fatalf("expected a forgotten key to be recorded again")
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var l *Log
+	l.Record(Entry{Event: "connect"})
+	l.Once("key", func() Entry { return Entry{Event: "send"} })
+}
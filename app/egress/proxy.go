@@ -0,0 +1,183 @@
+// Package egress implements the egress proxy of the gatekeeper. The network
+// traffic of the tracee is routed through it, so egress can be controlled by
+// host names instead of by IP addresses.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/utils"
+)
+
+// Proxy is an HTTP CONNECT proxy. Clients ask it to open a tunnel to a host
+// and port, e.g. with the HTTPS_PROXY environment variable. Other requests,
+// like plain HTTP forwarding, are refused.
+type Proxy struct {
+	// AllowedHosts restricts tunnels to these hosts and their subdomains.
+	// Without any, every tunnel is denied.
+	AllowedHosts []string
+	// Dial opens the connection to the upstream host. It defaults to a
+	// net.Dialer.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Log receives one line per request and tunnel. Nothing is logged if it
+	// is nil.
+	Log io.Writer
+	// Audit records the hosts tunnels were requested for, if set.
+	Audit *audit.Log
+
+	listener net.Listener
+	server   *http.Server
+	logMu    sync.Mutex
+}
+
+// Listen creates a Proxy listening on addr, e.g. "127.0.0.1:3128". Serve
+// must be called to accept connections.
+func Listen(addr string, allowedHosts []string) (*Proxy, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the egress proxy on %s: %w", addr, err)
+	}
+
+	p := &Proxy{AllowedHosts: allowedHosts, listener: ln}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	return p, nil
+}
+
+// Addr returns the address the proxy listens on.
+func (p *Proxy) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Serve accepts connections until Close is called.
+func (p *Proxy) Serve() error {
+	err := p.server.Serve(p.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close stops accepting connections. Open tunnels are not closed.
+func (p *Proxy) Close() error {
+	return p.server.Close()
+}
+
+// ServeHTTP opens a tunnel for a CONNECT request to an allowed host.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		p.logf("%s %s from %s denied: only CONNECT is supported", r.Method, r.URL, r.RemoteAddr)
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		p.logf("CONNECT %s from %s denied: %s", r.Host, r.RemoteAddr, err)
+		http.Error(w, "invalid CONNECT authority", http.StatusBadRequest)
+		return
+	}
+	if !utils.MatchDomain(p.AllowedHosts, host) {
+		p.logf("CONNECT %s from %s denied: host is not allowed", r.Host, r.RemoteAddr)
+		p.audit(r, "", audit.Denied)
+		http.Error(w, "host is not allowed", http.StatusForbidden)
+		return
+	}
+
+	dial := p.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	upstream, err := dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.logf("CONNECT %s from %s failed: %s", r.Host, r.RemoteAddr, err)
+		http.Error(w, "unable to reach host", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunnels are not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	p.logf("CONNECT %s from %s allowed to %s", r.Host, r.RemoteAddr, upstream.RemoteAddr())
+	p.audit(r, upstream.RemoteAddr().String(), audit.Allowed)
+	// Tunnels stay open as long as the client and the host want them to.
+	_ = client.SetDeadline(time.Time{})
+
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	go p.tunnel(r.Host, client, upstream, buffered.Reader)
+}
+
+// tunnel copies data between client and upstream until both directions are
+// done. Data the client sent after the CONNECT request, and which is already
+// buffered, is sent first.
+func (p *Proxy) tunnel(host string, client net.Conn, upstream net.Conn, buffered io.Reader) {
+	var sent, received int64
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sent, _ = io.Copy(upstream, io.MultiReader(buffered, client))
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		received, _ = io.Copy(client, upstream)
+		closeWrite(client)
+	}()
+	wg.Wait()
+
+	client.Close()
+	upstream.Close()
+	p.logf("CONNECT %s closed after sending %d and receiving %d bytes", host, sent, received)
+}
+
+// closeWrite signals the end of the data to the peer of conn.
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = c.CloseWrite()
+		return
+	}
+	_ = conn.Close()
+}
+
+// audit records the CONNECT request r and the address of the host it was
+// tunneled to, if any, in the audit log of the proxy.
+func (p *Proxy) audit(r *http.Request, remote string, decision string) {
+	p.Audit.Record(audit.Entry{
+		Event:    "proxy",
+		Protocol: "tcp",
+		Local:    r.RemoteAddr,
+		Remote:   remote,
+		Host:     r.Host,
+		Decision: decision,
+	})
+}
+
+// logf writes a line to the log of the proxy.
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.Log == nil {
+		return
+	}
+	p.logMu.Lock()
+	defer p.logMu.Unlock()
+	fmt.Fprintf(p.Log, "egress proxy: "+format+"\n", args...)
+}
@@ -0,0 +1,156 @@
+package egress
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cuandari/lib/app/audit"
+)
+
+// syncBuffer is a bytes.Buffer that can be written by the goroutines of the
+// proxy while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startProxy starts a proxy that connects every tunnel to an echo server.
+func startProxy(t *testing.T, allowedHosts []string) (*Proxy, *syncBuffer) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { echo.Close() })
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	p, err := Listen("127.0.0.1:0", allowedHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &syncBuffer{}
+	p.Log = log
+	p.Dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, echo.Addr().String())
+	}
+	go p.Serve()
+	t.Cleanup(func() { p.Close() })
+	return p, log
+}
+
+// request sends a request to the proxy and returns the connection and the
+// status code of the response.
+func request(t *testing.T, p *Proxy, method string, target string) (net.Conn, *bufio.Reader, int) {
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	fmt.Fprintf(conn, "%s %s HTTP/1.1\r\nHost: %s\r\n\r\n", method, target, strings.TrimPrefix(target, "http://"))
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, &http.Request{Method: method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, r, resp.StatusCode
+}
+
+func TestProxyTunnel(t *testing.T) {
+	p, log := startProxy(t, []string{"example.com"})
+
+	conn, r, status := request(t, p, http.MethodConnect, "api.example.com:443")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	if _, err := io.WriteString(conn, "ping\n"); err != nil {
+		t.Fatal(err)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Fatalf("expected the tunnel to echo ping, got %q %v", line, err)
+	}
+	if !strings.Contains(log.String(), "CONNECT api.example.com:443 from") {
+		t.Fatalf("expected the request to be logged, got %q", log.String())
+	}
+}
+
+func TestProxyDenied(t *testing.T) {
+	p, log := startProxy(t, []string{"example.com"})
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{http.MethodConnect, "evil.test:443", http.StatusForbidden},
+		{http.MethodConnect, "example.com.evil.test:443", http.StatusForbidden},
+		{http.MethodConnect, "example.com", http.StatusBadRequest},
+		{http.MethodGet, "http://example.com", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if _, _, status := request(t, p, tt.method, tt.target); status != tt.want {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.target, tt.want, status)
+		}
+	}
+	if !strings.Contains(log.String(), "CONNECT evil.test:443 from") {
+		t.Fatalf("expected the denied request to be logged, got %q", log.String())
+	}
+}
+
+func TestProxyAudit(t *testing.T) {
+	p, _ := startProxy(t, []string{"example.com"})
+	log := &syncBuffer{}
+	p.Audit = audit.New(log)
+
+	request(t, p, http.MethodConnect, "api.example.com:443")
+	request(t, p, http.MethodConnect, "evil.test:443")
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit entries, got %q", log.String())
+	}
+	if !strings.Contains(lines[0], `"host":"api.example.com:443"`) || !strings.Contains(lines[0], `"decision":"allowed"`) {
+		t.Fatalf("expected the tunnel to be audited, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"host":"evil.test:443"`) || !strings.Contains(lines[1], `"decision":"denied"`) {
+		t.Fatalf("expected the denied request to be audited, got %q", lines[1])
+	}
+}
+
+func TestProxyWithoutAllowlist(t *testing.T) {
+	p, _ := startProxy(t, nil)
+
+	if _, _, status := request(t, p, http.MethodConnect, "anything.test:443"); status != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", status)
+	}
+}
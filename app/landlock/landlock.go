@@ -0,0 +1,182 @@
+//go:build linux
+
+// Package landlock restricts filesystem access of the calling thread and
+// its future children with the Linux Landlock LSM.
+package landlock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/cuandari/lib/app/utils"
+	"golang.org/x/sys/unix"
+)
+
+// ErrUnsupported is returned if the kernel does not support Landlock.
+var ErrUnsupported = errors.New("landlock is not supported by the kernel")
+
+const (
+	// accessRead contains the rights required to read files and list directories.
+	accessRead = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	// accessWriteV1 contains the write rights known to Landlock ABI 1.
+	accessWriteV1 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+
+	// accessFile contains the rights that can be granted on regular files.
+	accessFile = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// Policy describes the filesystem access granted to the restricted thread.
+type Policy struct {
+	// Read grants reading files and listing directories.
+	Read bool
+	// Write grants creating, modifying and removing files and directories.
+	Write bool
+	// Paths limits Read and Write to the given files and directories. If
+	// empty, access is granted on the whole filesystem.
+	Paths []string
+	// Executables are always allowed to be read and executed, so that the
+	// restricted thread can still start the tracee.
+	Executables []string
+}
+
+// ABIVersion returns the Landlock ABI version of the running kernel or 0
+// if Landlock is not supported or disabled.
+func ABIVersion() int {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(abi)
+}
+
+// handledAccess returns the rights known to the given ABI version. Rights
+// that are not handled by a ruleset are implicitly allowed, which lets the
+// ruleset degrade gracefully on older kernels.
+func handledAccess(abi int) uint64 {
+	access := uint64(accessRead | accessWriteV1)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	return access
+}
+
+// grantedAccess returns the rights the policy grants on its paths.
+func (p Policy) grantedAccess() uint64 {
+	var access uint64
+	if p.Read {
+		access |= accessRead
+	}
+	if p.Write {
+		access |= accessWriteV1 |
+			unix.LANDLOCK_ACCESS_FS_REFER |
+			unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	return access
+}
+
+// RestrictThread applies the policy to the calling OS thread. Processes
+// forked by the thread afterwards inherit the restriction. Callers must
+// lock the goroutine to its thread and must not unlock it again, so that
+// the Go runtime retires the thread once the goroutine exits.
+func RestrictThread(p Policy) error {
+	abi := ABIVersion()
+	if abi < 1 {
+		return ErrUnsupported
+	}
+	handled := handledAccess(abi)
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	// Only pass the fields of the attribute the kernel knows about.
+	size := unsafe.Sizeof(attr.Access_fs)
+	rulesetFd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), size, 0)
+	if errno != 0 {
+		return fmt.Errorf("create landlock ruleset: %w", errno)
+	}
+	ruleset := os.NewFile(rulesetFd, "landlock ruleset")
+	defer utils.SafeClose(ruleset, "landlock ruleset")
+
+	granted := p.grantedAccess() & handled
+	if granted != 0 {
+		paths := p.Paths
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		for _, path := range paths {
+			if err := addPathRule(int(rulesetFd), path, granted); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, exe := range p.Executables {
+		access := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE|unix.LANDLOCK_ACCESS_FS_READ_FILE) & handled
+		if err := addPathRule(int(rulesetFd), exe, access); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return fmt.Errorf("restrict thread with landlock ruleset: %w", errno)
+	}
+	return nil
+}
+
+// addPathRule allows access beneath path. Paths that do not exist are
+// skipped, as nothing can be accessed through them yet.
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open landlock path %s: %w", path, err)
+	}
+	defer func() {
+		_ = unix.Close(fd)
+	}()
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("stat landlock path %s: %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		// Directory rights cannot be granted on files
+		access &= accessFile
+	}
+	if access == 0 {
+		return nil
+	}
+
+	rule := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(fd),
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("add landlock rule for %s: %w", path, errno)
+	}
+	return nil
+}
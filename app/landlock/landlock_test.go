@@ -0,0 +1,58 @@
+//go:build linux
+
+package landlock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestHandledAccessDegradesOnOlderABIs(t *testing.T) {
+	tests := []struct {
+		name     string
+		abi      int
+		refer    bool
+		truncate bool
+	}{
+		{"abi 1", 1, false, false},
+		{"abi 2", 2, true, false},
+		{"abi 3", 3, true, true},
+		{"abi 6", 6, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			access := handledAccess(tt.abi)
+			a.NotZero(access & unix.LANDLOCK_ACCESS_FS_READ_FILE)
+			a.NotZero(access & unix.LANDLOCK_ACCESS_FS_WRITE_FILE)
+			a.Equal(tt.refer, access&unix.LANDLOCK_ACCESS_FS_REFER != 0)
+			a.Equal(tt.truncate, access&unix.LANDLOCK_ACCESS_FS_TRUNCATE != 0)
+		})
+	}
+}
+
+func TestGrantedAccessReadOnly(t *testing.T) {
+	a := assert.New(t)
+	access := Policy{Read: true}.grantedAccess()
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_READ_FILE)
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_READ_DIR)
+	a.Zero(access & unix.LANDLOCK_ACCESS_FS_WRITE_FILE)
+	a.Zero(access & unix.LANDLOCK_ACCESS_FS_REMOVE_FILE)
+}
+
+func TestGrantedAccessWrite(t *testing.T) {
+	a := assert.New(t)
+	access := Policy{Read: true, Write: true}.grantedAccess()
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_READ_FILE)
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_WRITE_FILE)
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_MAKE_REG)
+	a.NotZero(access & unix.LANDLOCK_ACCESS_FS_TRUNCATE)
+}
+
+func TestGrantedAccessNone(t *testing.T) {
+	a := assert.New(t)
+	a.Zero(Policy{}.grantedAccess())
+}
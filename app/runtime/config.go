@@ -2,6 +2,9 @@ package runtime
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	sec "github.com/seccomp/libseccomp-golang"
@@ -14,11 +17,22 @@ const (
 	EXECUTION_MODE_RUN   EXECUTION_MODE = iota + 1
 )
 
+// BACKEND selects the kernel mechanism used to intercept syscalls.
+type BACKEND string
+
+const (
+	BACKEND_PTRACE         BACKEND = "ptrace"
+	BACKEND_SECCOMP_NOTIFY BACKEND = "seccomp-notify"
+)
+
 type SyscallConfig struct {
 	SyscallsAllowList              []string `split_words:"true"`
 	SyscallsAllowMap               map[string]bool
 	SyscallsKillTargetIfNotAllowed bool `split_words:"true" default:"true"`
 	SyscallsDenyTargetIfNotAllowed bool `split_words:"true" default:"false"`
+	// SyscallsAllowForeignAbi allows syscalls made with an ABI other than
+	// the native one, e.g. i386 and x32 syscalls on x86-64.
+	SyscallsAllowForeignAbi bool `split_words:"true" default:"false"`
 }
 
 type FsConfig struct {
@@ -27,15 +41,68 @@ type FsConfig struct {
 	// FileSystemAllowedPaths, when non-empty, restricts filesystem access to
 	// the provided list of directories (whitelist). Paths should be absolute.
 	FileSystemAllowedPaths []string `split_words:"true"`
+	// FileSystemUseLandlock additionally enforces the filesystem permissions
+	// with a Landlock ruleset if the kernel supports it.
+	FileSystemUseLandlock bool `split_words:"true" default:"true"`
 }
 
 type NetworkConfig struct {
 	NetworkAllowClient bool `split_words:"true" default:"false"`
 	NetworkAllowServer bool `split_words:"true" default:"false"`
 	LocalSocketsAllow  bool `split_words:"true" default:"false"`
+	// NetworkAllowLoopback allows AF_INET and AF_INET6 sockets that only
+	// connect and send to loopback addresses, and only bind and listen on
+	// them.
+	NetworkAllowLoopback bool `split_words:"true" default:"false"`
+	// NetworkAllowRawSockets allows SOCK_RAW sockets of AF_INET and
+	// AF_INET6, and AF_PACKET sockets, in addition to client or server
+	// permissions.
+	NetworkAllowRawSockets bool `split_words:"true" default:"false"`
+	// LocalSocketsNetlinkProtocols lists the netlink protocols besides
+	// NETLINK_ROUTE that may be used, e.g. "audit" or "NETLINK_GENERIC".
+	LocalSocketsNetlinkProtocols []string `split_words:"true"`
+	// LocalSocketsAllowFdPassing allows passing fds over Unix sockets with
+	// SCM_RIGHTS control messages.
+	LocalSocketsAllowFdPassing bool `split_words:"true" default:"false"`
+	// LocalSocketsFdPassingPaths, when non-empty, restricts fd passing to
+	// Unix sockets bound or connected to one of these paths. Abstract
+	// socket names start with "@".
+	LocalSocketsFdPassingPaths []string `split_words:"true"`
+	// NetworkAllowedDomains, when non-empty, restricts DNS queries sent to
+	// port 53 to these domains and their subdomains.
+	NetworkAllowedDomains []string `split_words:"true"`
+	// NetworkLogDeniedDomains logs DNS queries outside
+	// NetworkAllowedDomains instead of denying them.
+	NetworkLogDeniedDomains bool `split_words:"true" default:"false"`
+	// NetworkAllowedHosts, when non-empty, restricts TCP connections to
+	// these hosts and their subdomains, as named by the TLS server name or
+	// the HTTP Host of the first data sent on a connection.
+	NetworkAllowedHosts []string `split_words:"true"`
+	// NetworkEgressProxy, when set, is the IP address and port of the egress
+	// proxy, e.g. "127.0.0.1:3128". It is the only AF_INET or AF_INET6
+	// address the tracee may connect or send to. Session.Exec points
+	// HTTPS_PROXY of the tracee to it, and the gatekeeper CLI starts the
+	// proxy.
+	NetworkEgressProxy string `split_words:"true"`
+	// NetworkAuditLog, when set, is the path of the file the gatekeeper CLI
+	// appends the network audit log to, one JSON entry per line.
+	NetworkAuditLog string `split_words:"true"`
+}
+
+// MemoryConfig holds opt-in hardening against injected code.
+type MemoryConfig struct {
+	// MemoryDenyWriteExecute denies memory that is writable and executable
+	// at the same time, executable anonymous memory once
+	// MemoryAnonymousExecWindow has passed, and executing memfds.
+	MemoryDenyWriteExecute bool `split_words:"true" default:"false"`
+	// MemoryAnonymousExecWindow is the time after the start of the session
+	// during which anonymous memory may still be made executable, e.g. for
+	// runtimes that generate trampolines while starting up.
+	MemoryAnonymousExecWindow time.Duration `split_words:"true" default:"0s"`
 }
 
 type GatekeeperConfig struct {
+	Backend                BACKEND        `split_words:"true" default:"ptrace"`
 	EnforceOnStartup       bool           `split_words:"true" default:"true"`
 	ExecutionMode          EXECUTION_MODE `env:"EXECUTION_MODE,enum=TRACE,RUN"`
 	TriggerEnforceLogMatch string         `split_words:"true" default:"true"`
@@ -46,10 +113,23 @@ type GatekeeperConfig struct {
 type Config struct {
 	FsConfig
 	GatekeeperConfig
+	MemoryConfig
 	NetworkConfig
 	SyscallConfig
 }
 
+// Clone returns a copy of c that shares no slices or maps with it.
+func (c Config) Clone() Config {
+	c.SyscallsAllowList = slices.Clone(c.SyscallsAllowList)
+	c.SyscallsAllowMap = maps.Clone(c.SyscallsAllowMap)
+	c.FileSystemAllowedPaths = slices.Clone(c.FileSystemAllowedPaths)
+	c.LocalSocketsNetlinkProtocols = slices.Clone(c.LocalSocketsNetlinkProtocols)
+	c.LocalSocketsFdPassingPaths = slices.Clone(c.LocalSocketsFdPassingPaths)
+	c.NetworkAllowedDomains = slices.Clone(c.NetworkAllowedDomains)
+	c.NetworkAllowedHosts = slices.Clone(c.NetworkAllowedHosts)
+	return c
+}
+
 var c *Config
 
 func Load() {
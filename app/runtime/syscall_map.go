@@ -116,9 +116,6 @@ var syscallMap = map[string][]string{
 		"getsid",
 		"getcwd",
 		"fchdir",
-		"io_uring_enter",
-		"io_uring_register",
-		"io_uring_setup",
 		"kill",
 		"nanosleep",
 		"pause",
@@ -337,6 +334,14 @@ var syscallMap = map[string][]string{
 		"get_robust_list",
 		"flock",
 	},
+	// Operations submitted through an io_uring ring are performed by the
+	// kernel without further syscalls, so none of the argument checks apply
+	// to them.
+	"IO Uring": {
+		"io_uring_enter",
+		"io_uring_register",
+		"io_uring_setup",
+	},
 	"Miscellaneous": {
 		// "landlock_add_rule",
 		// "landlock_create_ruleset",
@@ -110,6 +110,12 @@ func (sal *SyscallAllowList) AllowProcessSynchronization() {
 	sal.Syscalls = append(sal.Syscalls, syscallMap["Synchronization"]...)
 }
 
+// AllowIoUring allows io_uring rings. The operations submitted through a
+// ring bypass the checks of the gatekeeper.
+func (sal *SyscallAllowList) AllowIoUring() {
+	sal.Syscalls = append(sal.Syscalls, syscallMap["IO Uring"]...)
+}
+
 func (sal *SyscallAllowList) AllowMisc() {
 	sal.Syscalls = append(sal.Syscalls, syscallMap["Miscellaneous"]...)
 }
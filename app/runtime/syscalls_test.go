@@ -23,6 +23,7 @@ func TestAllowProcessManagement(t *testing.T) {
 
 	a.NotEmpty(sal.Syscalls)
 	a.Contains(sal.Syscalls, "fork")
+	a.NotContains(sal.Syscalls, "io_uring_setup")
 }
 
 func TestAllowNetworkClient(t *testing.T) {
@@ -125,3 +126,13 @@ func TestAllowMisc(t *testing.T) {
 	a.NotEmpty(sal.Syscalls)
 	a.Contains(sal.Syscalls, "getrandom")
 }
+
+func TestAllowIoUring(t *testing.T) {
+	a := assert.New(t)
+	sal := NewSyscallAllowList()
+	sal.AllowIoUring()
+
+	a.NotEmpty(sal.Syscalls)
+	a.Contains(sal.Syscalls, "io_uring_setup")
+	a.Contains(sal.Syscalls, "io_uring_enter")
+}
@@ -0,0 +1,67 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// The arch_<GOARCH>.go files map syscall numbers, arguments and return
+// values to the registers of each architecture:
+//
+//	nativeArch and compatArches
+//	syscallArch(regs) sec.ScmpArch
+//	syscallNumber(regs) int
+//	syscallArgs(regs) SyscallArguments
+//	syscallReturn(regs) uintptr
+//	setSyscallReturn(regs, ret)
+//	rewindSyscall(regs, nr, args...)
+//	skipSyscall(pid, regs) error
+//
+// Registers are always read and written with PTRACE_GETREGSET and
+// PTRACE_SETREGSET, since arm64 and riscv64 have no PTRACE_GETREGS.
+//
+// syscallArch guesses the ABI from the registers. It is only used if
+// PTRACE_GET_SYSCALL_INFO is not available, because it cannot detect all
+// foreign syscalls, e.g. int 0x80 syscalls of 64-bit processes.
+
+// getRegs reads the general purpose registers of the stopped tracee pid.
+func getRegs(pid int, regs *unix.PtraceRegs) error {
+	return unix.PtraceGetRegs(pid, regs)
+}
+
+// setRegs writes the general purpose registers of the stopped tracee pid.
+func setRegs(pid int, regs *unix.PtraceRegs) error {
+	return unix.PtraceSetRegs(pid, regs)
+}
+
+// errnoReturn is the raw return value of a syscall that failed with errno.
+func errnoReturn(errno unix.Errno) uintptr {
+	return uintptr(-int(errno))
+}
+
+// x32SyscallBit is set in the numbers of x32 syscalls, which otherwise use
+// the x86-64 audit arch.
+const x32SyscallBit = 0x40000000
+
+// archFromAudit returns the syscall table of the AUDIT_ARCH_* value of a
+// syscall with number nr.
+func archFromAudit(auditArch uint32, nr uint64) sec.ScmpArch {
+	switch auditArch {
+	case unix.AUDIT_ARCH_X86_64:
+		if nr&x32SyscallBit != 0 {
+			return sec.ArchX32
+		}
+		return sec.ArchAMD64
+	case unix.AUDIT_ARCH_I386:
+		return sec.ArchX86
+	case unix.AUDIT_ARCH_AARCH64:
+		return sec.ArchARM64
+	case unix.AUDIT_ARCH_ARM:
+		return sec.ArchARM
+	case unix.AUDIT_ARCH_RISCV64:
+		return sec.ArchRISCV64
+	}
+	return sec.ArchInvalid
+}
@@ -0,0 +1,88 @@
+//go:build linux && amd64
+
+package uroot
+
+import (
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// nativeArch is the syscall table of 64-bit processes.
+const nativeArch = sec.ArchAMD64
+
+// compatArches are the syscall tables of the i386 and x32 ABIs, which can be
+// used by any process on x86-64.
+var compatArches = []sec.ScmpArch{sec.ArchX86, sec.ArchX32}
+
+// ia32CodeSegment is the code segment selector of 32-bit processes.
+const ia32CodeSegment = 0x23
+
+// syscallArch detects i386 syscalls of 32-bit processes by their code
+// segment and x32 syscalls by their number.
+func syscallArch(regs *unix.PtraceRegs) sec.ScmpArch {
+	if regs.Cs == ia32CodeSegment {
+		return sec.ArchX86
+	}
+	if regs.Orig_rax&x32SyscallBit != 0 {
+		return sec.ArchX32
+	}
+	return nativeArch
+}
+
+// syscallNumber returns the number of the syscall. orig_rax keeps the number
+// after rax was overwritten with the return value.
+func syscallNumber(regs *unix.PtraceRegs) int {
+	return int(int32(regs.Orig_rax))
+}
+
+// syscallArgs returns the arguments in rdi, rsi, rdx, r10, r8 and r9. i386
+// syscalls pass them in ebx, ecx, edx, esi, edi and ebp.
+func syscallArgs(regs *unix.PtraceRegs, arch sec.ScmpArch) SyscallArguments {
+	if arch == sec.ArchX86 {
+		return SyscallArguments{
+			{uintptr(uint32(regs.Rbx))},
+			{uintptr(uint32(regs.Rcx))},
+			{uintptr(uint32(regs.Rdx))},
+			{uintptr(uint32(regs.Rsi))},
+			{uintptr(uint32(regs.Rdi))},
+			{uintptr(uint32(regs.Rbp))},
+		}
+	}
+	return SyscallArguments{
+		{uintptr(regs.Rdi)},
+		{uintptr(regs.Rsi)},
+		{uintptr(regs.Rdx)},
+		{uintptr(regs.R10)},
+		{uintptr(regs.R8)},
+		{uintptr(regs.R9)},
+	}
+}
+
+// syscallReturn returns the return value in rax.
+func syscallReturn(regs *unix.PtraceRegs) uintptr {
+	return uintptr(regs.Rax)
+}
+
+func setSyscallReturn(regs *unix.PtraceRegs, ret uintptr) {
+	regs.Rax = uint64(ret)
+}
+
+// rewindSyscall changes the registers of a tracee in a syscall-exit-stop, so
+// that it executes its syscall instruction again, this time making the
+// syscall nr with args.
+func rewindSyscall(regs *unix.PtraceRegs, nr int, args ...uintptr) {
+	// syscall and int 0x80 are both two bytes long
+	regs.Rip -= 2
+	regs.Rax = uint64(nr)
+	argRegs := []*uint64{&regs.Rdi, &regs.Rsi, &regs.Rdx, &regs.R10, &regs.R8, &regs.R9}
+	for i, arg := range args {
+		*argRegs[i] = uint64(arg)
+	}
+}
+
+// skipSyscall makes the kernel skip the syscall the tracee is about to
+// enter. rax is left untouched, so it is returned to the tracee.
+func skipSyscall(pid int, regs *unix.PtraceRegs) error {
+	regs.Orig_rax = ^uint64(0)
+	return setRegs(pid, regs)
+}
@@ -0,0 +1,130 @@
+//go:build linux && amd64
+
+package uroot
+
+import (
+	"testing"
+
+	sec "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// Register snapshots of openat(AT_FDCWD, "/etc/hostname", O_RDONLY|O_CLOEXEC).
+var (
+	amd64OpenatEnter = unix.PtraceRegs{
+		Orig_rax: unix.SYS_OPENAT,
+		Rax:      uint64(errnoReturn(unix.ENOSYS)),
+		Rdi:      0xffffffffffffff9c,
+		Rsi:      0x7ffc8f2e1f3a,
+		Rdx:      unix.O_RDONLY | unix.O_CLOEXEC,
+		R10:      0,
+		R8:       0x7f3a2c1e8a80,
+		R9:       0x1,
+		Rip:      0x7f3a2c0f1e2b,
+		Rsp:      0x7ffc8f2e0c48,
+		Cs:       0x33,
+	}
+	amd64OpenatExit = unix.PtraceRegs{
+		Orig_rax: unix.SYS_OPENAT,
+		Rax:      3,
+		Rdi:      0xffffffffffffff9c,
+		Rsi:      0x7ffc8f2e1f3a,
+		Rdx:      unix.O_RDONLY | unix.O_CLOEXEC,
+		Rip:      0x7f3a2c0f1e2b,
+		Rsp:      0x7ffc8f2e0c48,
+	}
+	amd64OpenatExitENOENT = unix.PtraceRegs{
+		Orig_rax: unix.SYS_OPENAT,
+		Rax:      uint64(errnoReturn(unix.ENOENT)),
+		Rdi:      0xffffffffffffff9c,
+		Rsi:      0x7ffc8f2e1f3a,
+		Rdx:      unix.O_RDONLY | unix.O_CLOEXEC,
+	}
+)
+
+func TestSyscallEventAmd64(t *testing.T) {
+	tests := []struct {
+		name  string
+		regs  unix.PtraceRegs
+		sysno int
+		arg0  uintptr
+		arg1  uintptr
+		ret   uintptr
+		errno unix.Errno
+	}{
+		{"enter", amd64OpenatEnter, unix.SYS_OPENAT, 0xffffffffffffff9c, 0x7ffc8f2e1f3a, errnoReturn(unix.ENOSYS), unix.ENOSYS},
+		{"exit", amd64OpenatExit, unix.SYS_OPENAT, 0xffffffffffffff9c, 0x7ffc8f2e1f3a, 3, 0},
+		{"exit with error", amd64OpenatExitENOENT, unix.SYS_OPENAT, 0xffffffffffffff9c, 0x7ffc8f2e1f3a, errnoReturn(unix.ENOENT), unix.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SyscallEvent{Regs: tt.regs}
+			s.FillArgs()
+			s.FillRet()
+
+			assert.Equal(t, tt.sysno, s.Sysno)
+			assert.Equal(t, tt.arg0, s.Args[0].Value)
+			assert.Equal(t, tt.arg1, s.Args[1].Value)
+			assert.Equal(t, uintptr(unix.O_RDONLY|unix.O_CLOEXEC), s.Args[2].Value)
+			assert.Equal(t, tt.ret, s.Ret[0].Value)
+			assert.Equal(t, tt.errno, s.Errno)
+		})
+	}
+}
+
+func TestSetSyscallReturnAmd64(t *testing.T) {
+	regs := amd64OpenatExit
+	setSyscallReturn(&regs, errnoReturn(unix.EPERM))
+
+	assert.Equal(t, ^uint64(0), regs.Rax)
+	assert.Equal(t, uint64(unix.SYS_OPENAT), regs.Orig_rax)
+	assert.Equal(t, amd64OpenatExit.Rdx, regs.Rdx)
+}
+
+func TestRewindSyscallAmd64(t *testing.T) {
+	regs := amd64OpenatExit
+	rewindSyscall(&regs, unix.SYS_CLOSE, 3)
+
+	assert.Equal(t, amd64OpenatExit.Rip-2, regs.Rip)
+	assert.Equal(t, uint64(unix.SYS_CLOSE), regs.Rax)
+	assert.Equal(t, uint64(3), regs.Rdi)
+	assert.Equal(t, amd64OpenatExit.Rsi, regs.Rsi)
+}
+
+// Register snapshot of open("/etc/hostname", O_RDONLY) made by a 32-bit
+// process with int 0x80.
+var amd64I386OpenEnter = unix.PtraceRegs{
+	Orig_rax: 5,
+	Rax:      uint64(errnoReturn(unix.ENOSYS)),
+	Rbx:      0xffd2a4c0,
+	Rcx:      unix.O_RDONLY,
+	Rdx:      0,
+	Rsi:      0xf7f2c000,
+	Rdi:      0xf7f2d000,
+	Rbp:      0xffd2a4a8,
+	Rip:      0xf7f4a549,
+	Rsp:      0xffd2a490,
+	Cs:       0x23,
+}
+
+func TestSyscallArchAmd64(t *testing.T) {
+	x32 := amd64OpenatEnter
+	x32.Orig_rax |= x32SyscallBit
+
+	assert.Equal(t, sec.ArchAMD64, syscallArch(&amd64OpenatEnter))
+	assert.Equal(t, sec.ArchX86, syscallArch(&amd64I386OpenEnter))
+	assert.Equal(t, sec.ArchX32, syscallArch(&x32))
+}
+
+func TestSyscallEventAmd64I386(t *testing.T) {
+	s := &SyscallEvent{Regs: amd64I386OpenEnter}
+	s.FillArgs()
+
+	assert.Equal(t, sec.ArchX86, s.Arch)
+	assert.Equal(t, 5, s.Sysno)
+	assert.Equal(t, uintptr(0xffd2a4c0), s.Args[0].Value)
+	assert.Equal(t, uintptr(unix.O_RDONLY), s.Args[1].Value)
+	assert.Equal(t, uintptr(0xffd2a4a8), s.Args[5].Value)
+}
@@ -0,0 +1,87 @@
+//go:build linux && arm64
+
+package uroot
+
+import (
+	"unsafe"
+
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// nativeArch is the syscall table of 64-bit processes.
+const nativeArch = sec.ArchARM64
+
+// compatArches are the syscall tables of 32-bit processes.
+var compatArches = []sec.ScmpArch{sec.ArchARM}
+
+// psrMode32Bit is set in pstate while executing 32-bit code.
+const psrMode32Bit = 0x10
+
+// syscallArch detects syscalls of 32-bit processes. Their registers do not
+// fit the 64-bit layout, so only the syscall info provides their number and
+// arguments.
+func syscallArch(regs *unix.PtraceRegs) sec.ScmpArch {
+	if regs.Pstate&psrMode32Bit != 0 {
+		return sec.ArchARM
+	}
+	return nativeArch
+}
+
+// syscallNumber returns the number of the syscall in x8.
+func syscallNumber(regs *unix.PtraceRegs) int {
+	return int(int32(regs.Regs[8]))
+}
+
+// syscallArgs returns the arguments in x0 to x5. x0 is overwritten with the
+// return value, so the first argument is only valid on syscall enter.
+func syscallArgs(regs *unix.PtraceRegs, arch sec.ScmpArch) SyscallArguments {
+	return SyscallArguments{
+		{uintptr(regs.Regs[0])},
+		{uintptr(regs.Regs[1])},
+		{uintptr(regs.Regs[2])},
+		{uintptr(regs.Regs[3])},
+		{uintptr(regs.Regs[4])},
+		{uintptr(regs.Regs[5])},
+	}
+}
+
+// syscallReturn returns the return value in x0.
+func syscallReturn(regs *unix.PtraceRegs) uintptr {
+	return uintptr(regs.Regs[0])
+}
+
+func setSyscallReturn(regs *unix.PtraceRegs, ret uintptr) {
+	regs.Regs[0] = uint64(ret)
+}
+
+// rewindSyscall changes the registers of a tracee in a syscall-exit-stop, so
+// that it executes its syscall instruction again, this time making the
+// syscall nr with args.
+func rewindSyscall(regs *unix.PtraceRegs, nr int, args ...uintptr) {
+	// svc is four bytes long
+	regs.Pc -= 4
+	regs.Regs[8] = uint64(nr)
+	for i, arg := range args {
+		regs.Regs[i] = uint64(arg)
+	}
+}
+
+// skipSyscall makes the kernel skip the syscall the tracee is about to
+// enter. Changing x8 has no effect on arm64, the syscall number has to be
+// replaced with the NT_ARM_SYSTEM_CALL register set instead. x0 is returned
+// to the tracee.
+func skipSyscall(pid int, regs *unix.PtraceRegs) error {
+	if err := setRegs(pid, regs); err != nil {
+		return err
+	}
+
+	nr := int32(-1)
+	iov := unix.Iovec{Base: (*byte)(unsafe.Pointer(&nr))}
+	iov.SetLen(int(unsafe.Sizeof(nr)))
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_SETREGSET, uintptr(pid), unix.NT_ARM_SYSTEM_CALL, uintptr(unsafe.Pointer(&iov)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+//go:build linux && arm64
+
+package uroot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// Register snapshots of openat(AT_FDCWD, "/etc/hostname", O_RDONLY|O_CLOEXEC).
+// x0 holds the first argument on enter and the return value on exit.
+var (
+	arm64OpenatEnter = unix.PtraceRegs{
+		Regs: [31]uint64{
+			0: 0xffffffffffffff9c,
+			1: 0xffffd2a4cf3a,
+			2: unix.O_RDONLY | unix.O_CLOEXEC,
+			3: 0,
+			4: 0xffff8e1c0a80,
+			5: 0x1,
+			8: unix.SYS_OPENAT,
+		},
+		Sp:     0xffffd2a4b8c0,
+		Pc:     0xffff8e0f1e2c,
+		Pstate: 0x80001000,
+	}
+	arm64OpenatExit = unix.PtraceRegs{
+		Regs: [31]uint64{
+			0: 3,
+			1: 0xffffd2a4cf3a,
+			2: unix.O_RDONLY | unix.O_CLOEXEC,
+			8: unix.SYS_OPENAT,
+		},
+		Sp: 0xffffd2a4b8c0,
+		Pc: 0xffff8e0f1e2c,
+	}
+	arm64OpenatExitENOENT = unix.PtraceRegs{
+		Regs: [31]uint64{
+			0: uint64(errnoReturn(unix.ENOENT)),
+			1: 0xffffd2a4cf3a,
+			2: unix.O_RDONLY | unix.O_CLOEXEC,
+			8: unix.SYS_OPENAT,
+		},
+	}
+)
+
+func TestSyscallEventArm64(t *testing.T) {
+	tests := []struct {
+		name  string
+		regs  unix.PtraceRegs
+		sysno int
+		arg0  uintptr
+		ret   uintptr
+		errno unix.Errno
+	}{
+		{"enter", arm64OpenatEnter, unix.SYS_OPENAT, 0xffffffffffffff9c, 0xffffffffffffff9c, 0},
+		{"exit", arm64OpenatExit, unix.SYS_OPENAT, 3, 3, 0},
+		{"exit with error", arm64OpenatExitENOENT, unix.SYS_OPENAT, errnoReturn(unix.ENOENT), errnoReturn(unix.ENOENT), unix.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SyscallEvent{Regs: tt.regs}
+			s.FillArgs()
+			s.FillRet()
+
+			assert.Equal(t, tt.sysno, s.Sysno)
+			assert.Equal(t, tt.arg0, s.Args[0].Value)
+			assert.Equal(t, uintptr(0xffffd2a4cf3a), s.Args[1].Value)
+			assert.Equal(t, uintptr(unix.O_RDONLY|unix.O_CLOEXEC), s.Args[2].Value)
+			assert.Equal(t, tt.ret, s.Ret[0].Value)
+			assert.Equal(t, tt.errno, s.Errno)
+		})
+	}
+}
+
+func TestSetSyscallReturnArm64(t *testing.T) {
+	regs := arm64OpenatExit
+	setSyscallReturn(&regs, errnoReturn(unix.EPERM))
+
+	assert.Equal(t, ^uint64(0), regs.Regs[0])
+	assert.Equal(t, uint64(unix.SYS_OPENAT), regs.Regs[8])
+	assert.Equal(t, arm64OpenatExit.Regs[1], regs.Regs[1])
+}
+
+func TestRewindSyscallArm64(t *testing.T) {
+	regs := arm64OpenatExit
+	rewindSyscall(&regs, unix.SYS_CLOSE, 3)
+
+	assert.Equal(t, arm64OpenatExit.Pc-4, regs.Pc)
+	assert.Equal(t, uint64(unix.SYS_CLOSE), regs.Regs[8])
+	assert.Equal(t, uint64(3), regs.Regs[0])
+	assert.Equal(t, arm64OpenatExit.Regs[1], regs.Regs[1])
+}
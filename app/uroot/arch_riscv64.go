@@ -0,0 +1,66 @@
+//go:build linux && riscv64
+
+package uroot
+
+import (
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// nativeArch is the syscall table of 64-bit processes.
+const nativeArch = sec.ArchRISCV64
+
+// compatArches is empty, as 32-bit processes are not supported.
+var compatArches []sec.ScmpArch
+
+// syscallArch always returns the native arch.
+func syscallArch(regs *unix.PtraceRegs) sec.ScmpArch {
+	return nativeArch
+}
+
+// syscallNumber returns the number of the syscall in a7.
+func syscallNumber(regs *unix.PtraceRegs) int {
+	return int(int32(regs.A7))
+}
+
+// syscallArgs returns the arguments in a0 to a5. a0 is overwritten with the
+// return value, so the first argument is only valid on syscall enter.
+func syscallArgs(regs *unix.PtraceRegs, arch sec.ScmpArch) SyscallArguments {
+	return SyscallArguments{
+		{uintptr(regs.A0)},
+		{uintptr(regs.A1)},
+		{uintptr(regs.A2)},
+		{uintptr(regs.A3)},
+		{uintptr(regs.A4)},
+		{uintptr(regs.A5)},
+	}
+}
+
+// syscallReturn returns the return value in a0.
+func syscallReturn(regs *unix.PtraceRegs) uintptr {
+	return uintptr(regs.A0)
+}
+
+func setSyscallReturn(regs *unix.PtraceRegs, ret uintptr) {
+	regs.A0 = uint64(ret)
+}
+
+// rewindSyscall changes the registers of a tracee in a syscall-exit-stop, so
+// that it executes its syscall instruction again, this time making the
+// syscall nr with args.
+func rewindSyscall(regs *unix.PtraceRegs, nr int, args ...uintptr) {
+	// ecall is four bytes long
+	regs.Pc -= 4
+	regs.A7 = uint64(nr)
+	argRegs := []*uint64{&regs.A0, &regs.A1, &regs.A2, &regs.A3, &regs.A4, &regs.A5}
+	for i, arg := range args {
+		*argRegs[i] = uint64(arg)
+	}
+}
+
+// skipSyscall makes the kernel skip the syscall the tracee is about to
+// enter. a0 is returned to the tracee.
+func skipSyscall(pid int, regs *unix.PtraceRegs) error {
+	regs.A7 = ^uint64(0)
+	return setRegs(pid, regs)
+}
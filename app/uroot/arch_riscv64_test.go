@@ -0,0 +1,90 @@
+//go:build linux && riscv64
+
+package uroot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// Register snapshots of openat(AT_FDCWD, "/etc/hostname", O_RDONLY|O_CLOEXEC).
+// a0 holds the first argument on enter and the return value on exit.
+var (
+	riscv64OpenatEnter = unix.PtraceRegs{
+		Pc: 0x3fa3e0f1e2c,
+		Ra: 0x3fa3e0c4a10,
+		Sp: 0x3ffd2a4b8c0,
+		A0: 0xffffffffffffff9c,
+		A1: 0x3ffd2a4cf3a,
+		A2: unix.O_RDONLY | unix.O_CLOEXEC,
+		A3: 0,
+		A4: 0x3fa3e1c0a80,
+		A5: 0x1,
+		A7: unix.SYS_OPENAT,
+	}
+	riscv64OpenatExit = unix.PtraceRegs{
+		Pc: 0x3fa3e0f1e2c,
+		Sp: 0x3ffd2a4b8c0,
+		A0: 3,
+		A1: 0x3ffd2a4cf3a,
+		A2: unix.O_RDONLY | unix.O_CLOEXEC,
+		A7: unix.SYS_OPENAT,
+	}
+	riscv64OpenatExitENOENT = unix.PtraceRegs{
+		A0: uint64(errnoReturn(unix.ENOENT)),
+		A1: 0x3ffd2a4cf3a,
+		A2: unix.O_RDONLY | unix.O_CLOEXEC,
+		A7: unix.SYS_OPENAT,
+	}
+)
+
+func TestSyscallEventRiscv64(t *testing.T) {
+	tests := []struct {
+		name  string
+		regs  unix.PtraceRegs
+		sysno int
+		arg0  uintptr
+		ret   uintptr
+		errno unix.Errno
+	}{
+		{"enter", riscv64OpenatEnter, unix.SYS_OPENAT, 0xffffffffffffff9c, 0xffffffffffffff9c, 0},
+		{"exit", riscv64OpenatExit, unix.SYS_OPENAT, 3, 3, 0},
+		{"exit with error", riscv64OpenatExitENOENT, unix.SYS_OPENAT, errnoReturn(unix.ENOENT), errnoReturn(unix.ENOENT), unix.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SyscallEvent{Regs: tt.regs}
+			s.FillArgs()
+			s.FillRet()
+
+			assert.Equal(t, tt.sysno, s.Sysno)
+			assert.Equal(t, tt.arg0, s.Args[0].Value)
+			assert.Equal(t, uintptr(0x3ffd2a4cf3a), s.Args[1].Value)
+			assert.Equal(t, uintptr(unix.O_RDONLY|unix.O_CLOEXEC), s.Args[2].Value)
+			assert.Equal(t, tt.ret, s.Ret[0].Value)
+			assert.Equal(t, tt.errno, s.Errno)
+		})
+	}
+}
+
+func TestSetSyscallReturnRiscv64(t *testing.T) {
+	regs := riscv64OpenatExit
+	setSyscallReturn(&regs, errnoReturn(unix.EPERM))
+
+	assert.Equal(t, ^uint64(0), regs.A0)
+	assert.Equal(t, uint64(unix.SYS_OPENAT), regs.A7)
+	assert.Equal(t, riscv64OpenatExit.A1, regs.A1)
+}
+
+func TestRewindSyscallRiscv64(t *testing.T) {
+	regs := riscv64OpenatExit
+	rewindSyscall(&regs, unix.SYS_CLOSE, 3)
+
+	assert.Equal(t, riscv64OpenatExit.Pc-4, regs.Pc)
+	assert.Equal(t, uint64(unix.SYS_CLOSE), regs.A7)
+	assert.Equal(t, uint64(3), regs.A0)
+	assert.Equal(t, riscv64OpenatExit.A1, regs.A1)
+}
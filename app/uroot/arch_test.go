@@ -0,0 +1,91 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	sec "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestArchFromAudit(t *testing.T) {
+	tests := []struct {
+		name      string
+		auditArch uint32
+		nr        uint64
+		want      sec.ScmpArch
+	}{
+		{"x86-64", unix.AUDIT_ARCH_X86_64, 257, sec.ArchAMD64},
+		{"x32", unix.AUDIT_ARCH_X86_64, x32SyscallBit | 257, sec.ArchX32},
+		{"i386", unix.AUDIT_ARCH_I386, 5, sec.ArchX86},
+		{"aarch64", unix.AUDIT_ARCH_AARCH64, 56, sec.ArchARM64},
+		{"arm", unix.AUDIT_ARCH_ARM, 5, sec.ArchARM},
+		{"riscv64", unix.AUDIT_ARCH_RISCV64, 56, sec.ArchRISCV64},
+		{"unknown", 0, 0, sec.ArchInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, archFromAudit(tt.auditArch, tt.nr))
+		})
+	}
+}
+
+func TestFillInfoEntry(t *testing.T) {
+	info := &syscallInfo{
+		Op:   unix.PTRACE_SYSCALL_INFO_ENTRY,
+		Arch: unix.AUDIT_ARCH_I386,
+		data: [8]uint64{5, 0xffd2a4c0, 0x80000, 0, 0, 0, 0},
+	}
+
+	s := &SyscallEvent{Arch: nativeArch, Sysno: 2}
+	s.fillInfo(info)
+
+	assert.Equal(t, sec.ArchX86, s.Arch)
+	assert.Equal(t, 5, s.Sysno)
+	assert.Equal(t, uintptr(0xffd2a4c0), s.Args[0].Value)
+	assert.Equal(t, uintptr(0x80000), s.Args[1].Value)
+}
+
+func TestFillInfoExit(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  [8]uint64
+		ret   uintptr
+		errno unix.Errno
+	}{
+		{"success", [8]uint64{3, 0}, 3, 0},
+		{"error", [8]uint64{uint64(errnoReturn(unix.ENOENT)), 1}, errnoReturn(unix.ENOENT), unix.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &syscallInfo{
+				Op:   unix.PTRACE_SYSCALL_INFO_EXIT,
+				Arch: unix.AUDIT_ARCH_I386,
+				data: tt.data,
+			}
+
+			s := &SyscallEvent{Arch: sec.ArchX86, Sysno: 5}
+			s.fillInfo(info)
+
+			assert.Equal(t, sec.ArchX86, s.Arch)
+			assert.Equal(t, 5, s.Sysno)
+			assert.Equal(t, tt.ret, s.Ret[0].Value)
+			assert.Equal(t, tt.errno, s.Errno)
+		})
+	}
+}
+
+func TestIsArchAllowed(t *testing.T) {
+	s := NewSession(&runtime.Config{})
+	assert.True(t, s.isArchAllowed(nativeArch))
+	assert.False(t, s.isArchAllowed(sec.ArchX86))
+	assert.False(t, s.isArchAllowed(sec.ArchInvalid))
+
+	s = NewSession(&runtime.Config{SyscallConfig: runtime.SyscallConfig{SyscallsAllowForeignAbi: true}})
+	assert.True(t, s.isArchAllowed(sec.ArchX86))
+}
@@ -0,0 +1,217 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	runtimeConfig "github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+// attachOptions are the ptrace options of seized processes. Unlike traced
+// children, seized processes are not killed if the gatekeeper exits.
+const attachOptions = unix.PTRACE_O_TRACEEXEC |
+	unix.PTRACE_O_TRACESYSGOOD |
+	unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_TRACEFORK | unix.PTRACE_O_TRACEVFORK
+
+// errDetach is returned by tracer.wait once the tracees should be detached.
+var errDetach = errors.New("detach requested")
+
+// Attach gatekeeps the already running process pid and the children it
+// creates from now on. All threads of the process are seized with
+// PTRACE_SEIZE. Once ctx is cancelled, the tracees are detached and keep
+// running.
+//
+// The returned context is cancelled with an *ExitEventError as cause once the
+// tracee exited or was detached.
+func (s *Session) Attach(ctx context.Context, pid int, recordCallback ...EventCallback) (context.Context, error) {
+	if s.config.Backend != runtimeConfig.BACKEND_PTRACE {
+		return nil, fmt.Errorf("attaching to pid %d requires the ptrace backend", pid)
+	}
+
+	// The tracees are detached when ctx is done, so the exit context must
+	// outlive it until detaching finished.
+	exitContext, cancel := context.WithCancelCause(context.Background())
+	seized := make(chan error, 1)
+	go func() {
+		// All ptrace requests must be made by the thread that seized
+		// the tracees.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		tracer := newTracer(s, recordCallback)
+		defer tracer.close()
+		tracer.done = ctx.Done()
+
+		if err := tracer.seize(pid); err != nil {
+			tracer.detachAll()
+			seized <- err
+			cancel(&ExitEventError{
+				ExitCode: 2,
+			})
+			return
+		}
+		seized <- nil
+
+		tracer.runLoop(cancel)
+	}()
+
+	if err := <-seized; err != nil {
+		return nil, err
+	}
+	return exitContext, nil
+}
+
+// seize attaches to all threads of pid. Threads that are created while
+// seizing are picked up by listing the threads again until no new ones are
+// found.
+func (t *tracer) seize(pid int) error {
+	// The standard streams are those of the process when attaching. Other
+	// fds opened before are unknown and looked up in /proc.
+	t.session.stdio = args.StdioIDs(pid)
+	fds := args.NewStdioFdTable(pid, t.session.stdio)
+	for {
+		tids, err := threadIDs(pid)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, tid := range tids {
+			if _, ok := t.processes[tid]; ok {
+				continue
+			}
+
+			if err := ptraceSeize(tid, attachOptions); err != nil {
+				switch {
+				case errors.Is(err, unix.ESRCH):
+					// the thread exited in the meantime
+					continue
+				case errors.Is(err, unix.EPERM) && tid != pid && len(t.processes) > 0:
+					// the thread was created by a seized thread
+					// and is already traced by us
+					continue
+				}
+				return fmt.Errorf("unable to seize thread %d of pid %d: %w", tid, pid, err)
+			}
+			found = true
+
+			// The interrupt stop allows to restart the thread with
+			// syscall stops enabled. We cannot know whether the thread
+			// is inside a syscall, so the next syscall stop is assumed to
+			// be an enter stop.
+			t.addProcess(tid, SyscallExit, fds)
+			if err := unix.PtraceInterrupt(tid); err != nil && !errors.Is(err, unix.ESRCH) {
+				return fmt.Errorf("unable to interrupt thread %d of pid %d: %w", tid, pid, err)
+			}
+		}
+
+		if !found {
+			if len(t.processes) < 1 {
+				return fmt.Errorf("no threads of pid %d found", pid)
+			}
+			return nil
+		}
+	}
+}
+
+// detachAll detaches from all tracees and leaves them running. Signals that
+// were about to be delivered to a tracee are passed on.
+func (t *tracer) detachAll() {
+	pids := make([]int, 0, len(t.processes))
+	for pid := range t.processes {
+		pids = append(pids, pid)
+	}
+
+	for len(pids) > 0 {
+		pid := pids[0]
+		pids = pids[1:]
+		delete(t.processes, pid)
+
+		if err := unix.PtraceInterrupt(pid); err != nil {
+			// the tracee exited already
+			continue
+		}
+
+		for {
+			var status unix.WaitStatus
+			if _, err := unix.Wait4(pid, &status, unix.WALL, nil); err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				break
+			}
+			if !status.Stopped() {
+				break
+			}
+
+			var signal unix.Signal
+			switch status.StopSignal() {
+			case unix.SIGTRAP | 0x80:
+			case unix.SIGTRAP:
+				switch status.TrapCause() {
+				case unix.PTRACE_EVENT_CLONE, unix.PTRACE_EVENT_FORK, unix.PTRACE_EVENT_VFORK:
+					// the new child is traced, too, and must
+					// be detached as well
+					if child, err := unix.PtraceGetEventMsg(pid); err == nil {
+						pids = append(pids, int(child))
+					}
+				case 0:
+					signal = unix.SIGTRAP
+				}
+			default:
+				// group-stops of seized tracees are event stops
+				// that have no signal to deliver
+				if int(status>>16) != unix.PTRACE_EVENT_STOP {
+					signal = status.StopSignal()
+				}
+			}
+
+			if err := ptraceDetach(pid, signal); err != nil {
+				fmt.Printf("Unable to detach from pid %d: %s\n", pid, err.Error())
+			}
+			break
+		}
+	}
+}
+
+// threadIDs lists the threads of pid.
+func threadIDs(pid int) ([]int, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list threads of pid %d: %w", pid, err)
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		tids = append(tids, tid)
+	}
+	return tids, nil
+}
+
+func ptraceSeize(pid int, options int) error {
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_SEIZE, uintptr(pid), 0, uintptr(options), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func ptraceDetach(pid int, signal unix.Signal) error {
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_DETACH, uintptr(pid), 0, uintptr(signal), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cuandari/lib/app/uroot/syscalls"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// untrackedFdSyscalls return new fds whose kind is not recorded. The fds are
+// forgotten, so that they are looked up in /proc instead of being mistaken
+// for a previous fd with the same number.
+var untrackedFdSyscalls = map[string]bool{
+	"memfd_create":      true,
+	"memfd_secret":      true,
+	"timerfd_create":    true,
+	"signalfd":          true,
+	"signalfd4":         true,
+	"inotify_init":      true,
+	"inotify_init1":     true,
+	"fanotify_init":     true,
+	"userfaultfd":       true,
+	"perf_event_open":   true,
+	"pidfd_open":        true,
+	"pidfd_getfd":       true,
+	"open_by_handle_at": true,
+	"open_tree":         true,
+	"fsopen":            true,
+	"fsmount":           true,
+	"fspick":            true,
+}
+
+// trackFds updates the shadow fd table of p after the syscall of the exit
+// stop rec returned.
+func trackFds(p *process, rec *TraceRecord) {
+	sc := rec.Syscall
+	name, err := sec.ScmpSyscall(sc.Sysno).GetNameByArch(sc.Arch)
+	if err != nil {
+		return
+	}
+
+	fds := p.fds
+	arg := func(i int) int { return int(sc.Args[i].Value) }
+	fd := int32(sc.Ret[0].Value)
+
+	// The fd is released even if close fails, e.g. with EINTR.
+	if name == "close" {
+		fds.Close(int32(arg(0)))
+		return
+	}
+	if name == "connect" {
+		// A non-blocking connect continues in the background.
+		if sc.Errno == 0 || sc.Errno == unix.EINPROGRESS {
+			trackPeer(p, rec)
+		}
+		return
+	}
+	if sc.Errno != 0 {
+		return
+	}
+
+	switch name {
+	case "open":
+		fds.Set(fd, fileFd(p.pid, fd, arg(1)))
+	case "creat":
+		fds.Set(fd, fileFd(p.pid, fd, unix.O_CREAT|unix.O_WRONLY|unix.O_TRUNC))
+	case "openat":
+		fds.Set(fd, fileFd(p.pid, fd, arg(2)))
+	case "openat2":
+		var how unix.OpenHow
+		if _, err := p.Read(Addr(sc.Args[2].Value), &how); err != nil {
+			fds.Close(fd)
+			return
+		}
+		fds.Set(fd, fileFd(p.pid, fd, int(how.Flags)))
+	case "socket":
+		fds.Set(fd, socketFd(arg(0), arg(1)))
+	case "socketpair":
+		var pair [2]int32
+		if _, err := p.Read(Addr(sc.Args[3].Value), &pair); err != nil {
+			return
+		}
+		fds.Set(pair[0], socketFd(arg(0), arg(1)))
+		fds.Set(pair[1], socketFd(arg(0), arg(1)))
+	case "accept", "accept4":
+		// The connection has the family and type of the listening
+		// socket. The tracee did not initiate it, so its data is not
+		// inspected for the host it is meant for.
+		fds.Dup(int32(arg(0)), fd, name == "accept4" && arg(3)&unix.SOCK_CLOEXEC != 0)
+		info, _ := fds.Get(fd)
+		info.Type = args.FDSocket
+		info.Sent = true
+		fds.Set(fd, info)
+	case "pipe", "pipe2":
+		var pair [2]int32
+		if _, err := p.Read(Addr(sc.Args[0].Value), &pair); err != nil {
+			return
+		}
+		closeOnExec := name == "pipe2" && arg(1)&unix.O_CLOEXEC != 0
+		fds.Set(pair[0], args.FdInfo{Type: args.FDPipe, Flags: unix.O_RDONLY, CloseOnExec: closeOnExec})
+		fds.Set(pair[1], args.FdInfo{Type: args.FDPipe, Flags: unix.O_WRONLY, CloseOnExec: closeOnExec})
+	case "dup":
+		fds.Dup(int32(arg(0)), fd, false)
+	case "dup2":
+		// dup2 of an fd to itself changes nothing
+		if arg(0) != arg(1) {
+			fds.Dup(int32(arg(0)), fd, false)
+		}
+	case "dup3":
+		fds.Dup(int32(arg(0)), fd, arg(2)&unix.O_CLOEXEC != 0)
+	case "fcntl":
+		switch arg(1) {
+		case unix.F_DUPFD:
+			fds.Dup(int32(arg(0)), fd, false)
+		case unix.F_DUPFD_CLOEXEC:
+			fds.Dup(int32(arg(0)), fd, true)
+		case unix.F_SETFD:
+			fds.SetCloseOnExec(int32(arg(0)), arg(2)&unix.FD_CLOEXEC != 0)
+		}
+	case "close_range":
+		first, last := uint32(arg(0)), uint32(arg(1))
+		for _, n := range fds.Fds() {
+			if uint32(n) < first || uint32(n) > last {
+				continue
+			}
+			if arg(2)&unix.CLOSE_RANGE_CLOEXEC != 0 {
+				fds.SetCloseOnExec(n, true)
+			} else {
+				fds.Close(n)
+			}
+		}
+	case "execve", "execveat":
+		fds.Exec()
+	case "eventfd", "eventfd2":
+		closeOnExec := name == "eventfd2" && arg(1)&unix.EFD_CLOEXEC != 0
+		fds.Set(fd, args.FdInfo{Type: args.FDAnonEvent, CloseOnExec: closeOnExec})
+	case "epoll_create", "epoll_create1":
+		closeOnExec := name == "epoll_create1" && arg(0)&unix.EPOLL_CLOEXEC != 0
+		fds.Set(fd, args.FdInfo{Type: args.FDAnonEventPoll, CloseOnExec: closeOnExec})
+	case "io_uring_setup":
+		fds.Set(fd, args.FdInfo{Type: args.FDAnonIoUring})
+	case "recvmsg", "recvmmsg":
+		trackReceivedFds(p, rec, name == "recvmmsg")
+	case "write", "writev", "sendto", "sendmsg", "sendmmsg":
+		if info, ok := fds.Get(int32(arg(0))); ok && info.Peer.IsValid() && !info.Sent {
+			info.Sent = true
+			fds.Set(int32(arg(0)), info)
+		}
+	default:
+		if untrackedFdSyscalls[name] {
+			fds.Close(fd)
+		}
+	}
+}
+
+// kcmpFiles is KCMP_FILES of kcmp(2), which compares the fd tables of two
+// processes.
+const kcmpFiles = 2
+
+// sharesFdTable returns true if the child created by the clone, clone3, fork
+// or vfork the tracee p is stopped in shares the fd table of p, i.e. if it
+// was created with CLONE_FILES. The ptrace event does not tell: clone with
+// SIGCHLD as exit signal is reported as a fork, and clone of a process without
+// CLONE_FILES as a clone.
+//
+// The kernel tables are compared with kcmp, as the flags of clone3 are read
+// from memory another thread of p can change after the kernel read them.
+// Without kcmp, e.g. if the kernel is built without it, the flags are used.
+func sharesFdTable(p *process, child int) bool {
+	same, _, errno := unix.Syscall6(unix.SYS_KCMP, uintptr(p.pid), uintptr(child), kcmpFiles, 0, 0, 0)
+	if errno == 0 {
+		return same == 0
+	}
+
+	flags, err := cloneFlags(p)
+	if err != nil {
+		fmt.Printf("Unable to read clone flags of pid %d, assuming a copied fd table: %s\n", p.pid, err.Error())
+		return false
+	}
+	return flags&unix.CLONE_FILES != 0
+}
+
+// cloneFlags returns the flags of the syscall that created a child of the
+// tracee p, which is stopped in it. fork and vfork have no flags.
+func cloneFlags(p *process) (uint64, error) {
+	var regs unix.PtraceRegs
+	if err := getRegs(p.pid, &regs); err != nil {
+		return 0, err
+	}
+	arch := syscallArch(&regs)
+	name, err := sec.ScmpSyscall(syscallNumber(&regs)).GetNameByArch(arch)
+	if err != nil {
+		return 0, err
+	}
+
+	arg := syscallArgs(&regs, arch)[0].Value
+	switch name {
+	case "clone":
+		return uint64(arg), nil
+	case "clone3":
+		// flags is the first field of struct clone_args
+		var flags uint64
+		_, err := p.Read(Addr(arg), &flags)
+		return flags, err
+	}
+	return 0, nil
+}
+
+// trackPeer records the address an AF_INET or AF_INET6 socket was connected
+// to by the connect of rec. Connecting to AF_UNSPEC dissolves the
+// association of a datagram socket.
+func trackPeer(p *process, rec *TraceRecord) {
+	sc := newSyscall(p, rec)
+	fd := sc.Args[0].Int()
+	info, ok := p.fds.Get(fd)
+	if !ok {
+		return
+	}
+	info.Peer, _ = syscalls.ReadInetSockaddr(sc.Reader, sc.Args[1].Pointer(), sc.Args[2].Uint(), false)
+	info.Sent = false
+	p.fds.Set(fd, info)
+}
+
+// trackReceivedFds records the fds passed to p with SCM_RIGHTS by the
+// recvmsg or recvmmsg of rec.
+func trackReceivedFds(p *process, rec *TraceRecord, mmsg bool) {
+	rights, _ := syscalls.ReceivedRights(newSyscall(p, rec), mmsg, int(rec.Syscall.Ret[0].Value))
+	for _, fd := range rights {
+		p.fds.Set(fd, receivedFd(p.pid, fd))
+	}
+}
+
+// receivedFd describes an fd of pid that was passed to it by another
+// process. Its flags are looked up in /proc.
+func receivedFd(pid int, fd int32) args.FdInfo {
+	info := fileFd(pid, fd, fdinfoFlags(pid, fd))
+	if info.Type != args.FDFile {
+		// Only files are described by their path.
+		info.Path = ""
+	}
+	return info
+}
+
+// fdinfoFlags returns the open flags of fd of pid, including O_CLOEXEC.
+func fdinfoFlags(pid int, fd int32) int {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/fdinfo/%d", pid, fd))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(line, "flags:"); ok {
+			flags, _ := strconv.ParseInt(strings.TrimSpace(v), 8, 64)
+			return int(flags)
+		}
+	}
+	return 0
+}
+
+// fileFd describes the fd of pid opened by path with flags. The file is
+// looked up in /proc once, when it is opened.
+func fileFd(pid int, fd int32, flags int) args.FdInfo {
+	path, _ := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, fd))
+	return args.FdInfo{
+		Type:        args.FdType(pid, fd),
+		Path:        path,
+		Flags:       flags,
+		CloseOnExec: flags&unix.O_CLOEXEC != 0,
+	}
+}
+
+// socketFd describes a socket created with socket(family, typ, protocol).
+// typ may include SOCK_NONBLOCK and SOCK_CLOEXEC.
+func socketFd(family int, typ int) args.FdInfo {
+	return args.FdInfo{
+		Type:        args.FDSocket,
+		Flags:       unix.O_RDWR,
+		Family:      family,
+		SockType:    typ &^ (unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC),
+		CloseOnExec: typ&unix.SOCK_CLOEXEC != 0,
+	}
+}
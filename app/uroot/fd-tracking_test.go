@@ -0,0 +1,106 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// exitRecord returns the exit stop of syscall nr with args that returned ret.
+func exitRecord(nr int, ret uintptr, errno unix.Errno, sysArgs ...uintptr) *TraceRecord {
+	rec := &TraceRecord{Event: SyscallExit, Syscall: &SyscallEvent{Arch: nativeArch, Sysno: nr, Errno: errno}}
+	for i, a := range sysArgs {
+		rec.Syscall.Args[i].Value = a
+	}
+	rec.Syscall.Ret[0].Value = ret
+	return rec
+}
+
+func TestTrackFds(t *testing.T) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fileFdNum := f.Fd()
+
+	p := &process{pid: os.Getpid(), fds: args.NewFdTable()}
+	p.fds.Set(20, args.FdInfo{Type: args.FDPipe})
+	p.fds.Set(21, args.FdInfo{Type: args.FDPipe})
+	steps := []*TraceRecord{
+		exitRecord(unix.SYS_OPENAT, fileFdNum, 0, uintptr(0), 0, unix.O_RDONLY|unix.O_CLOEXEC),
+		exitRecord(unix.SYS_SOCKET, 10, 0, unix.AF_INET, unix.SOCK_STREAM|unix.SOCK_NONBLOCK),
+		exitRecord(unix.SYS_DUP3, 11, 0, 10, 11, unix.O_CLOEXEC),
+		exitRecord(unix.SYS_FCNTL, 12, 0, 20, unix.F_DUPFD, 0),
+		exitRecord(unix.SYS_CLOSE, 0, 0, 21),
+		exitRecord(unix.SYS_SOCKET, uintptr(errnoReturn(unix.EACCES)), unix.EACCES, unix.AF_PACKET, unix.SOCK_RAW),
+	}
+	for _, rec := range steps {
+		trackFds(p, rec)
+	}
+
+	file, ok := p.fds.Get(int32(fileFdNum))
+	assert.True(t, ok)
+	assert.Equal(t, args.FDFile, file.Type)
+	assert.Equal(t, "/proc/"+strconv.Itoa(os.Getpid())+"/status", file.Path)
+	assert.True(t, file.CloseOnExec)
+
+	sock, ok := p.fds.Get(10)
+	assert.True(t, ok)
+	assert.Equal(t, args.FdInfo{Type: args.FDSocket, Flags: unix.O_RDWR, Family: unix.AF_INET, SockType: unix.SOCK_STREAM}, sock)
+
+	dup, _ := p.fds.Get(11)
+	assert.Equal(t, unix.AF_INET, dup.Family)
+	assert.True(t, dup.CloseOnExec)
+
+	pipe, _ := p.fds.Get(12)
+	assert.Equal(t, args.FDPipe, pipe.Type)
+
+	_, ok = p.fds.Get(21)
+	assert.False(t, ok)
+
+	trackFds(p, exitRecord(unix.SYS_EXECVE, 0, 0))
+	assert.ElementsMatch(t, []int32{10, 12, 20}, p.fds.Fds())
+}
+
+func TestRebuildFdTables(t *testing.T) {
+	// fd 1 was recorded as stdout, but no longer refers to a standard
+	// stream of the session when enforcement starts.
+	stale := args.NewFdTable()
+	stale.Set(1, args.FdInfo{Type: args.FDFile, Stdio: true})
+	tr := &tracer{session: &Session{}, processes: make(map[int]*process)}
+	tr.addProcess(os.Getpid(), SyscallExit, stale)
+	tr.addProcess(os.Getpid()+1, SyscallExit, stale)
+	tr.addProcess(os.Getpid()+2, SyscallExit, stale.Clone())
+
+	tr.rebuildFdTables()
+
+	thread, other := tr.processes[os.Getpid()+1].fds, tr.processes[os.Getpid()+2].fds
+	assert.NotSame(t, stale, tr.processes[os.Getpid()].fds)
+	assert.Same(t, tr.processes[os.Getpid()].fds, thread)
+	assert.NotSame(t, thread, other)
+	_, ok := thread.Get(1)
+	assert.False(t, ok)
+}
+
+func TestSharesFdTable(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	p := &process{pid: os.Getpid()}
+	assert.True(t, sharesFdTable(p, unix.Gettid()))
+	assert.False(t, sharesFdTable(p, cmd.Process.Pid))
+}
@@ -0,0 +1,138 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/cuandari/lib/app/landlock"
+	"github.com/cuandari/lib/app/utils"
+	"golang.org/x/sys/unix"
+)
+
+// landlockPolicy turns the filesystem permissions into a Landlock policy.
+// It returns false if Landlock should not be used. The executables are
+// always allowed to be executed.
+func (s *Session) landlockPolicy(executables ...string) (landlock.Policy, bool) {
+	conf := s.config
+	if !conf.FileSystemUseLandlock || !conf.EnforceOnStartup {
+		return landlock.Policy{}, false
+	}
+
+	// The kernel opens the ELF interpreter of the tracee itself. Grant it
+	// like the executables to be able to start dynamically linked binaries.
+	for _, exe := range executables {
+		if interp := elfInterpreter(exe); interp != "" {
+			executables = append(executables, interp)
+		}
+	}
+
+	return landlock.Policy{
+		Read:        conf.FileSystemAllowRead,
+		Write:       conf.FileSystemAllowWrite,
+		Paths:       conf.FileSystemAllowedPaths,
+		Executables: executables,
+	}, true
+}
+
+// restrictFileSystem applies the Landlock policy to the calling thread, so
+// that the tracee started from it inherits the ruleset. The ptrace and
+// seccomp checks still apply on top of it. It returns whether the thread was
+// restricted; in that case the caller must not unlock the thread.
+//
+// The ruleset cannot be lifted again. It must not be applied to the tracer,
+// which reads files in /proc that the ruleset might deny, see
+// startRestricted.
+func restrictFileSystem(policy landlock.Policy) bool {
+	err := landlock.RestrictThread(policy)
+	if errors.Is(err, landlock.ErrUnsupported) {
+		println("Landlock is not supported by the kernel. Enforcing filesystem permissions via syscall checks only.")
+		return false
+	} else if err != nil {
+		// The thread might be partially restricted at this point, so it
+		// has to be retired nonetheless.
+		fmt.Printf("Unable to apply landlock ruleset: %s. Enforcing filesystem permissions via syscall checks only.\n", err.Error())
+		return true
+	}
+
+	println(fmt.Sprintf("Landlock ABI %d ruleset applied", landlock.ABIVersion()))
+	return true
+}
+
+// startRestricted starts the traced process c with the Landlock policy
+// applied and makes the calling thread its tracer. A tracee started with
+// PTRACE_TRACEME is traced by the thread that forked it, so c is started from
+// a throwaway thread that is restricted first. Once c stopped after execve,
+// it is detached with SIGSTOP, which keeps it from running, and seized by
+// the calling thread. Tracing a process in a Landlock domain is allowed to
+// unrestricted threads.
+func startRestricted(c *exec.Cmd, policy landlock.Policy) error {
+	started := make(chan error, 1)
+	go func() {
+		// The thread is never unlocked, so it exits with the goroutine
+		// instead of being reused.
+		runtime.LockOSThread()
+		restrictFileSystem(policy)
+
+		if err := startTraced(c); err != nil {
+			started <- err
+			return
+		}
+		if err := ptraceDetach(c.Process.Pid, unix.SIGSTOP); err != nil {
+			_ = c.Process.Kill()
+			started <- fmt.Errorf("unable to hand over pid %d to the tracer: %w", c.Process.Pid, err)
+			return
+		}
+		started <- nil
+	}()
+	if err := <-started; err != nil {
+		return err
+	}
+
+	pid := c.Process.Pid
+	if err := ptraceSeize(pid, 0); err != nil {
+		_ = c.Process.Kill()
+		return fmt.Errorf("unable to seize pid %d: %w", pid, err)
+	}
+
+	// The SIGSTOP is reported as signal-delivery-stop, or as
+	// PTRACE_EVENT_STOP if the tracee entered the group-stop before it was
+	// seized. Either way it is not passed on when the tracee is resumed.
+	var ws unix.WaitStatus
+	if _, err := unix.Wait4(pid, &ws, unix.WALL, nil); err != nil {
+		return fmt.Errorf("received error while waiting for pid %d to stop after seizing it: %w", pid, err)
+	} else if !ws.Stopped() {
+		return fmt.Errorf("expected pid %d to be stopped but got %#+v", pid, ws)
+	}
+	return nil
+}
+
+// elfInterpreter returns the program interpreter of an ELF executable or an
+// empty string for static binaries and scripts.
+func elfInterpreter(path string) string {
+	f, err := elf.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer utils.SafeClose(f, "elf executable")
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		b := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(b, 0); err != nil {
+			return ""
+		}
+		// the interpreter path is NUL terminated
+		if len(b) > 0 && b[len(b)-1] == 0 {
+			b = b[:len(b)-1]
+		}
+		return string(b)
+	}
+	return ""
+}
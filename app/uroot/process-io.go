@@ -1,6 +1,11 @@
 package uroot
 
 import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/cuandari/lib/app/utils"
 	"golang.org/x/sys/unix"
 )
 
@@ -28,3 +33,35 @@ func (p *processIo) Read(b []byte) (int, error) {
 func newProcReader(pid int, addr uintptr) *processIo {
 	return &processIo{pid: pid, addr: addr}
 }
+
+// A procMemIo implements io.Reader on top of /proc/<pid>/mem. It is used
+// when the tracee is not ptrace-stopped, e.g. by the seccomp-notify backend.
+type procMemIo struct {
+	mem   *os.File
+	addr  uintptr
+	bytes int
+}
+
+// Read implements io.Read for a procMemIo.
+func (p *procMemIo) Read(b []byte) (int, error) {
+	n, err := p.mem.ReadAt(b, int64(p.addr))
+	if err != nil && n == 0 {
+		return n, err
+	}
+	p.addr += uintptr(n)
+	p.bytes += n
+	return n, nil
+}
+
+// readProcMem reads from the memory of pid at addr into v.
+func readProcMem(pid int, addr uintptr, v interface{}) (int, error) {
+	mem, err := os.Open(fmt.Sprintf("/proc/%d/mem", pid))
+	if err != nil {
+		return 0, fmt.Errorf("open memory of pid %d: %w", pid, err)
+	}
+	defer utils.SafeClose(mem, "tracee memory")
+
+	r := &procMemIo{mem: mem, addr: addr}
+	err = binary.Read(r, binary.NativeEndian, v)
+	return r.bytes, err
+}
@@ -0,0 +1,228 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/cuandari/lib/app/landlock"
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// SeccompExecCommand is the hidden subcommand the gatekeeper re-executes
+// itself with to install the seccomp filter before executing the tracee.
+const SeccompExecCommand = "seccomp-exec"
+
+// Values of the default-action flag of the seccomp-exec command.
+const (
+	seccompDefaultNotify = "notify"
+	seccompDefaultErrno  = "errno"
+	seccompDefaultKill   = "kill"
+)
+
+// seccompAlwaysAllowed are syscalls a process must always be able to make.
+// Denying them would leave the tracee unable to terminate.
+var seccompAlwaysAllowed = []string{"exit", "exit_group"}
+
+// seccompAlwaysNotify are syscalls that are always reported to the
+// gatekeeper. The first execve also makes the helper wait until the
+// gatekeeper has picked up the listener fd.
+var seccompAlwaysNotify = []string{"execve", "execveat"}
+
+// SeccompExec installs a seccomp filter with a user notification listener
+// and executes the tracee. It is executed in the child process started by
+// the seccomp-notify backend and does not return on success.
+//
+// The listener fd cannot be sent to the gatekeeper after the filter is
+// loaded, because the syscalls needed to do so might themselves wait for the
+// gatekeeper. Instead, the fd number is announced before loading the filter
+// and the gatekeeper copies it with pidfd_getfd(2).
+func SeccompExec(args []string) error {
+	fs := flag.NewFlagSet(SeccompExecCommand, flag.ContinueOnError)
+	allow := fs.String("allow", "", "Comma separated list of syscalls to allow")
+	notify := fs.String("notify", "", "Comma separated list of syscalls to report to the gatekeeper")
+	defaultAction := fs.String("default-action", seccompDefaultNotify, "Action for all other syscalls: notify, errno or kill")
+	badArchAction := fs.String("bad-arch-action", seccompDefaultKill, "Action for syscalls of foreign ABIs: notify, errno or kill")
+	foreignAbi := fs.Bool("foreign-abi", false, "Apply the rules to syscalls of foreign ABIs, too")
+	syncFd := fs.Int("sync-fd", -1, "Fd of the pipe to announce the notification listener fd on")
+	useLandlock := fs.Bool("landlock", false, "Apply a Landlock ruleset before executing the binary")
+	var policy landlock.Policy
+	fs.BoolVar(&policy.Read, "landlock-read", false, "Allow reading files with Landlock")
+	fs.BoolVar(&policy.Write, "landlock-write", false, "Allow writing files with Landlock")
+	fs.Func("landlock-path", "Restrict Landlock permissions to this path (repeatable)", func(v string) error {
+		policy.Paths = append(policy.Paths, v)
+		return nil
+	})
+	fs.Func("landlock-exec", "Allow executing this file with Landlock (repeatable)", func(v string) error {
+		policy.Executables = append(policy.Executables, v)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parse %s arguments: %w", SeccompExecCommand, err)
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("%s requires the binary to execute", SeccompExecCommand)
+	}
+	if *syncFd < 0 {
+		return fmt.Errorf("%s requires the fd of the sync pipe", SeccompExecCommand)
+	}
+
+	action, err := seccompAction(*defaultAction)
+	if err != nil {
+		return err
+	}
+	badArch, err := seccompAction(*badArchAction)
+	if err != nil {
+		return err
+	}
+
+	// Filters are installed per thread. Load and execve must happen on
+	// the same thread for the tracee to inherit the filter.
+	runtime.LockOSThread()
+
+	if *useLandlock {
+		restrictFileSystem(policy)
+	}
+
+	filter, err := sec.NewFilter(action)
+	if err != nil {
+		return fmt.Errorf("create seccomp filter: %w", err)
+	}
+	if err := filter.SetBadArchAction(badArch); err != nil {
+		return fmt.Errorf("set seccomp bad arch action: %w", err)
+	}
+	if *foreignAbi {
+		for _, arch := range compatArches {
+			if err := filter.AddArch(arch); err != nil {
+				return fmt.Errorf("add seccomp arch %s: %w", arch, err)
+			}
+		}
+	}
+
+	notified := splitSyscallNames(*notify)
+	notified = append(notified, seccompAlwaysNotify...)
+	if err := addSeccompRules(filter, notified, sec.ActNotify, nil); err != nil {
+		return err
+	}
+
+	allowed := append(splitSyscallNames(*allow), seccompAlwaysAllowed...)
+	if err := addSeccompRules(filter, allowed, sec.ActAllow, notified); err != nil {
+		return err
+	}
+
+	listenerFd, err := nextFreeFd(*syncFd)
+	if err != nil {
+		return err
+	}
+	if err := announceListenerFd(*syncFd, listenerFd); err != nil {
+		return err
+	}
+
+	if err := filter.Load(); err != nil {
+		return fmt.Errorf("load seccomp filter: %w", err)
+	}
+
+	notifFd, err := filter.GetNotifFd()
+	if err != nil {
+		return fmt.Errorf("get seccomp notification fd: %w", err)
+	}
+	if int(notifFd) != listenerFd {
+		return fmt.Errorf("seccomp notification fd is %d but %d was announced", notifFd, listenerFd)
+	}
+
+	bin := fs.Arg(0)
+	return syscall.Exec(bin, fs.Args(), os.Environ())
+}
+
+// landlockArgs encodes the policy as seccomp-exec flags.
+func landlockArgs(policy landlock.Policy) []string {
+	args := []string{
+		"-landlock",
+		"-landlock-read=" + strconv.FormatBool(policy.Read),
+		"-landlock-write=" + strconv.FormatBool(policy.Write),
+	}
+	for _, path := range policy.Paths {
+		args = append(args, "-landlock-path="+path)
+	}
+	for _, exe := range policy.Executables {
+		args = append(args, "-landlock-exec="+exe)
+	}
+	return args
+}
+
+func seccompAction(name string) (sec.ScmpAction, error) {
+	switch name {
+	case seccompDefaultNotify:
+		return sec.ActNotify, nil
+	case seccompDefaultErrno:
+		return sec.ActErrno.SetReturnCode(int16(unix.EPERM)), nil
+	case seccompDefaultKill:
+		return sec.ActKillProcess, nil
+	}
+	return sec.ActInvalid, fmt.Errorf("invalid default action %s", name)
+}
+
+func splitSyscallNames(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// addSeccompRules adds a rule with the given action for each syscall
+// unless it is listed in skip. Unknown syscall names are ignored because
+// not every syscall exists on every architecture.
+func addSeccompRules(filter *sec.ScmpFilter, names []string, action sec.ScmpAction, skip []string) error {
+	seen := make(map[string]bool)
+	for _, name := range skip {
+		seen[name] = true
+	}
+
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		sc, err := sec.GetSyscallFromName(name)
+		if err != nil {
+			continue
+		}
+		if err := filter.AddRule(sc, action); err != nil {
+			return fmt.Errorf("add seccomp rule for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// nextFreeFd returns the lowest unused fd, which is the fd the kernel will
+// assign to the notification listener. It duplicates an fd instead of
+// opening a file, which a Landlock ruleset might not permit.
+func nextFreeFd(syncFd int) (int, error) {
+	fd, err := unix.Dup(syncFd)
+	if err != nil {
+		return -1, fmt.Errorf("probe next free fd: %w", err)
+	}
+	if err := unix.Close(fd); err != nil {
+		return -1, fmt.Errorf("close probe fd %d: %w", fd, err)
+	}
+	return fd, nil
+}
+
+// announceListenerFd writes the listener fd number to the sync pipe syncFd.
+func announceListenerFd(syncFd int, fd int) error {
+	// The sync pipe must not leak into the tracee.
+	unix.CloseOnExec(syncFd)
+
+	if _, err := unix.Write(syncFd, []byte(strconv.Itoa(fd)+"\n")); err != nil {
+		return fmt.Errorf("announce seccomp listener fd: %w", err)
+	}
+	return nil
+}
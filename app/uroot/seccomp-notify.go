@@ -0,0 +1,339 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cuandari/lib/app/uroot/syscalls"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"github.com/cuandari/lib/app/utils"
+	sec "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// listenerFdTimeout bounds how long the gatekeeper waits for the tracee to
+// install its seccomp filter.
+const listenerFdTimeout = 5 * time.Second
+
+// notifiedProcess is a process that is blocked in a syscall reported via
+// seccomp user notification. It is not ptrace-stopped, so memory is read
+// through /proc/<pid>/mem.
+type notifiedProcess struct {
+	pid int
+}
+
+// Name implements Task.Name.
+func (p *notifiedProcess) Name() string {
+	return fmt.Sprintf("[pid %d]", p.pid)
+}
+
+// Read implements Task.Read.
+func (p *notifiedProcess) Read(addr Addr, v interface{}) (int, error) {
+	return readProcMem(p.pid, uintptr(addr), v)
+}
+
+// wrapSeccompNotifyCommand changes c so that its binary is executed by the
+// gatekeeper's seccomp-exec helper with a filter matching the session
+// configuration.
+func (s *Session) wrapSeccompNotifyCommand(c *exec.Cmd) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve gatekeeper executable: %w", err)
+	}
+
+	allow, notify := s.seccompFilterLists()
+	helperArgs := []string{
+		self,
+		SeccompExecCommand,
+		"-allow=" + strings.Join(allow, ","),
+		"-notify=" + strings.Join(notify, ","),
+		// SeccompNotify appends the sync pipe to the ExtraFiles of c,
+		// which start at fd 3 and may hold files of the caller.
+		"-sync-fd=" + strconv.Itoa(3+len(c.ExtraFiles)),
+		"-default-action=" + s.seccompDefaultAction(),
+		// Syscalls of foreign ABIs are denied like any other syscall
+		// that is not allowed.
+		"-bad-arch-action=" + s.seccompDefaultAction(),
+	}
+	if s.config.SyscallsAllowForeignAbi {
+		helperArgs = append(helperArgs, "-foreign-abi")
+	}
+	// The helper applies the Landlock ruleset itself once it is running,
+	// as its own shared libraries might not be covered by the ruleset.
+	if policy, ok := s.landlockPolicy(c.Path); ok {
+		helperArgs = append(helperArgs, landlockArgs(policy)...)
+	}
+	helperArgs = append(helperArgs, "--", c.Path)
+	if len(c.Args) > 1 {
+		helperArgs = append(helperArgs, c.Args[1:]...)
+	}
+
+	c.Path = self
+	c.Args = helperArgs
+	return nil
+}
+
+// seccompFilterLists splits the allowed syscalls into those the kernel can
+// allow directly and those whose arguments the gatekeeper has to inspect.
+func (s *Session) seccompFilterLists() (allow []string, notify []string) {
+	for name, allowed := range s.config.SyscallsAllowMap {
+		if !allowed {
+			continue
+		}
+		if isInspectedSyscall(name) {
+			notify = append(notify, name)
+		} else {
+			allow = append(allow, name)
+		}
+	}
+	return allow, notify
+}
+
+func (s *Session) seccompDefaultAction() string {
+	if !s.config.EnforceOnStartup {
+		// Enforcement starts later, so every syscall must pass the
+		// gatekeeper until then.
+		return seccompDefaultNotify
+	}
+	if s.config.SyscallsDenyTargetIfNotAllowed {
+		return seccompDefaultErrno
+	}
+	return seccompDefaultKill
+}
+
+// SeccompNotify starts c, which must have been prepared with
+// wrapSeccompNotifyCommand, and answers the seccomp user notifications of c and
+// its children until c exits.
+//
+// Unlike Trace, the tracee is not ptrace'd and can therefore be debugged or
+// use ptrace itself.
+func (s *Session) SeccompNotify(c *exec.Cmd, cancelFunc context.CancelCauseFunc, recordCallback ...EventCallback) {
+	syncR, syncW, err := os.Pipe()
+	if err != nil {
+		fmt.Printf("Unable to create seccomp sync pipe %s\n", err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 2,
+		})
+		return
+	}
+	defer utils.SafeClose(syncR, "seccomp sync pipe reader")
+	c.ExtraFiles = append(c.ExtraFiles, syncW)
+
+	if err := c.Start(); err != nil {
+		utils.SafeClose(syncW, "seccomp sync pipe writer")
+		fmt.Printf("Unable to start process %s\n", err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 2,
+		})
+		return
+	}
+	utils.SafeClose(syncW, "seccomp sync pipe writer")
+
+	pid := c.Process.Pid
+	notifFd, err := receiveListenerFd(pid, syncR)
+	if err != nil {
+		fmt.Printf("Unable to get seccomp listener of pid %d: %s\n", pid, err.Error())
+		_ = c.Process.Kill()
+		cancelFunc(&ExitEventError{
+			ExitCode: 2,
+		})
+		return
+	}
+
+	// The helper passes its standard streams on to the tracee.
+	s.stdio = args.StdioIDs(pid)
+	go s.notifyLoop(notifFd, pid)
+
+	state, err := c.Process.Wait()
+	utils.SafeClose(os.NewFile(uintptr(notifFd), "seccomp listener"), "seccomp listener")
+	if err != nil {
+		fmt.Printf("Unable to wait for pid %d: %s\n", pid, err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 3,
+		})
+		return
+	}
+
+	status := unix.WaitStatus(state.Sys().(syscall.WaitStatus))
+	rec := &TraceRecord{
+		PID:  pid,
+		Time: time.Now(),
+	}
+	if status.Signaled() {
+		rec.Event = SignalExit
+		rec.SignalExit = &SignalEvent{
+			Signal: status.Signal(),
+		}
+	} else {
+		rec.Event = Exit
+		rec.Exit = &ExitEvent{
+			WaitStatus: status,
+		}
+	}
+
+	p := &notifiedProcess{pid: pid}
+	for _, cb := range recordCallback {
+		cb(p, rec)
+	}
+
+	if rec.Event == SignalExit {
+		cancelFunc(&ExitEventError{
+			Signal: signalString(rec.SignalExit.Signal),
+		})
+		return
+	}
+	cancelFunc(&ExitEventError{
+		ExitCode: status.ExitStatus(),
+	})
+}
+
+// receiveListenerFd reads the announced listener fd number of pid and
+// copies the fd into the gatekeeper once the filter has been loaded.
+func receiveListenerFd(pid int, sync *os.File) (sec.ScmpFd, error) {
+	line, err := bufio.NewReader(sync).ReadString('\n')
+	if err != nil {
+		return -1, fmt.Errorf("read announced listener fd: %w", err)
+	}
+	targetFd, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return -1, fmt.Errorf("parse announced listener fd %q: %w", line, err)
+	}
+
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		return -1, fmt.Errorf("open pidfd: %w", err)
+	}
+	defer utils.SafeClose(os.NewFile(uintptr(pidfd), "pidfd"), "pidfd")
+
+	deadline := time.Now().Add(listenerFdTimeout)
+	for time.Now().Before(deadline) {
+		fd, err := unix.PidfdGetfd(pidfd, targetFd, 0)
+		if err == nil {
+			if isSeccompListener(fd) {
+				return sec.ScmpFd(fd), nil
+			}
+			_ = unix.Close(fd)
+		} else if !errors.Is(err, unix.EBADF) {
+			return -1, fmt.Errorf("copy fd %d: %w", targetFd, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return -1, fmt.Errorf("listener fd %d was not installed within %s", targetFd, listenerFdTimeout)
+}
+
+func isSeccompListener(fd int) bool {
+	link, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		return false
+	}
+	return link == "anon_inode:seccomp notify"
+}
+
+// notifyLoop answers notifications until the listener is closed.
+func (s *Session) notifyLoop(fd sec.ScmpFd, helperPid int) {
+	helperExecuted := false
+
+	for {
+		req, err := sec.NotifReceive(fd)
+		if err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				// the tracee was killed while we were about to receive
+				continue
+			}
+			return
+		}
+
+		name, err := req.Data.Syscall.GetNameByArch(req.Data.Arch)
+		if err != nil {
+			fmt.Printf("Unknown syscall detected: %s %d\n", err.Error(), req.Data.Syscall)
+			respondDenied(fd, req)
+			continue
+		}
+
+		// The helper's execve of the tracee mirrors the initial execve
+		// that is not reported by the ptrace backend.
+		if !helperExecuted && int(req.Pid) == helperPid && name == "execve" {
+			helperExecuted = true
+			respondAllowed(fd, req)
+			continue
+		}
+
+		s.addSyscallToCollection(uint64(req.Data.Syscall), name)
+
+		if !s.IsEnforced() {
+			respondAllowed(fd, req)
+			continue
+		}
+
+		allow := s.isArchAllowed(req.Data.Arch)
+		if allow {
+			allow = s.isSyscallAllowed(name, notifiedSyscall(req), true)
+		} else {
+			fmt.Printf("Syscall %s of foreign ABI %s not allowed\n", name, req.Data.Arch)
+		}
+
+		// The tracee might have been replaced by another process reusing
+		// its pid while we were inspecting its memory.
+		if err := sec.NotifIDValid(fd, req.ID); err != nil {
+			continue
+		}
+
+		if allow {
+			respondAllowed(fd, req)
+			continue
+		}
+
+		fmt.Println("Syscall not allowed:", name)
+		if !s.config.SyscallsDenyTargetIfNotAllowed {
+			_ = unix.Kill(int(req.Pid), unix.SIGKILL)
+		}
+		respondDenied(fd, req)
+	}
+}
+
+func notifiedSyscall(req *sec.ScmpNotifReq) syscalls.Syscall {
+	var sargs syscalls.SyscallArguments
+	for i := 0; i < len(sargs) && i < len(req.Data.Args); i++ {
+		sargs[i] = syscalls.SyscallArgument{Value: uintptr(req.Data.Args[i])}
+	}
+
+	pid := int(req.Pid)
+	return syscalls.Syscall{
+		Args:      sargs,
+		TraceePID: pid,
+		Reader: func(addr syscalls.Addr, v interface{}) (int, error) {
+			return readProcMem(pid, uintptr(addr), v)
+		},
+	}
+}
+
+func respondAllowed(fd sec.ScmpFd, req *sec.ScmpNotifReq) {
+	respond(fd, &sec.ScmpNotifResp{
+		ID:    req.ID,
+		Flags: sec.NotifRespFlagContinue,
+	})
+}
+
+func respondDenied(fd sec.ScmpFd, req *sec.ScmpNotifReq) {
+	respond(fd, &sec.ScmpNotifResp{
+		ID:    req.ID,
+		Error: int32(unix.EPERM),
+	})
+}
+
+func respond(fd sec.ScmpFd, resp *sec.ScmpNotifResp) {
+	if err := sec.NotifRespond(fd, resp); err != nil && !errors.Is(err, unix.ENOENT) {
+		fmt.Printf("Unable to respond to seccomp notification %d: %s\n", resp.ID, err.Error())
+	}
+}
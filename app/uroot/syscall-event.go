@@ -3,6 +3,7 @@ package uroot
 import (
 	"time"
 
+	sec "github.com/seccomp/libseccomp-golang"
 	"golang.org/x/sys/unix"
 )
 
@@ -12,6 +13,10 @@ type SyscallEvent struct {
 	// recorded.
 	Regs unix.PtraceRegs
 
+	// Arch is the syscall table Sysno belongs to. It differs from the
+	// native one for syscalls of foreign ABIs, e.g. i386 on x86-64.
+	Arch sec.ScmpArch
+
 	// Sysno is the syscall number.
 	Sysno int
 
@@ -38,21 +43,33 @@ type SyscallEvent struct {
 // from the point of view of the caller. The performance improvement is
 // negligible, as you can see by a look at the GNU runtime.
 func (s *SyscallEvent) FillArgs() {
-	s.Args = SyscallArguments{
-		{uintptr(s.Regs.Rdi)},
-		{uintptr(s.Regs.Rsi)},
-		{uintptr(s.Regs.Rdx)},
-		{uintptr(s.Regs.R10)},
-		{uintptr(s.Regs.R8)},
-		{uintptr(s.Regs.R9)},
+	s.Arch = syscallArch(&s.Regs)
+	s.Args = syscallArgs(&s.Regs, s.Arch)
+	s.Sysno = syscallNumber(&s.Regs)
+}
+
+// fillInfo overrides the values taken from the registers with the ABI aware
+// information of PTRACE_GET_SYSCALL_INFO.
+func (s *SyscallEvent) fillInfo(info *syscallInfo) {
+	if !info.isEntry() {
+		s.Ret = [2]SyscallArgument{{uintptr(info.rval())}}
+		s.Errno = 0
+		if info.isError() {
+			s.Errno = unix.Errno(-info.rval())
+		}
+		return
 	}
-	s.Sysno = int(uint32(s.Regs.Orig_rax))
+	s.Arch = archFromAudit(info.Arch, info.nr())
+	s.Sysno = int(int32(info.nr()))
+	s.Args = info.args()
 }
 
 // FillRet fills the TraceRecord with the result values from the registers.
 func (s *SyscallEvent) FillRet() {
-	s.Ret = [2]SyscallArgument{{uintptr(s.Regs.Rax)}, {uintptr(s.Regs.Rdx)}}
-	if errno := int(s.Regs.Rax); errno < 0 {
+	ret := syscallReturn(&s.Regs)
+	s.Ret = [2]SyscallArgument{{ret}}
+	// errors are returned as values between -4095 and -1
+	if errno := int(ret); errno < 0 && errno > -4096 {
 		s.Errno = unix.Errno(-errno)
 	}
 }
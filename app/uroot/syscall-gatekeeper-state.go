@@ -1,31 +1,98 @@
 package uroot
 
-var enforced = false
-var syscallsBeforeEnforce = make(map[string]int64)
-var syscallsAfterEnforce = make(map[string]int64)
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
 
-func enforceGatekeeper() {
-	enforced = true
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
+// Session holds the policy, the enforcement state and the syscall
+// statistics of one gatekept process tree. Sessions are independent of each
+// other, so several of them can be used in one program.
+type Session struct {
+	config   *runtime.Config
+	enforced atomic.Bool
+	// started is when the session was created.
+	started time.Time
+
+	// stdio identifies the standard streams the tracee was started with.
+	// It is set before syscalls are checked.
+	stdio []args.FileID
+	// audit records the network endpoints of the tracee, if set.
+	audit *audit.Log
+
+	mu                    sync.Mutex
+	syscallsBeforeEnforce map[string]int64
+	syscallsAfterEnforce  map[string]int64
+}
+
+// NewSession creates a session that enforces the given configuration.
+// Enforcement starts immediately if config.EnforceOnStartup is set.
+func NewSession(config *runtime.Config) *Session {
+	s := &Session{
+		config:                config,
+		started:               time.Now(),
+		syscallsBeforeEnforce: make(map[string]int64),
+		syscallsAfterEnforce:  make(map[string]int64),
+	}
+	if config.EnforceOnStartup {
+		s.Enforce()
+	}
+	return s
+}
+
+// Config returns the configuration enforced by the session.
+func (s *Session) Config() *runtime.Config {
+	return s.config
+}
+
+// SetNetworkAudit records the connect, bind, accept and first send to each
+// destination of the tracee, and the hosts its traffic is for, in l. It must
+// be called before the tracee is started. Only the ptrace backend sees the
+// result of accept, so Start refuses the seccomp-notify backend with it.
+func (s *Session) SetNetworkAudit(l *audit.Log) {
+	s.audit = l
+}
+
+// Enforce starts enforcing the policy.
+func (s *Session) Enforce() {
+	s.enforced.Store(true)
 }
 
-func GetIsGatekeeperEnforced() bool {
-	return enforced
+// IsEnforced returns whether the policy is enforced.
+func (s *Session) IsEnforced() bool {
+	return s.enforced.Load()
 }
 
-func addSyscallToCollection(rax uint64, name string) {
+func (s *Session) addSyscallToCollection(rax uint64, name string) {
 	// key := fmt.Sprintf("%d->%s", rax, name)
 	key := name
-	if enforced {
-		syscallsAfterEnforce[key] = syscallsAfterEnforce[key] + 1
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.IsEnforced() {
+		s.syscallsAfterEnforce[key] = s.syscallsAfterEnforce[key] + 1
 	} else {
-		syscallsBeforeEnforce[key] = syscallsBeforeEnforce[key] + 1
+		s.syscallsBeforeEnforce[key] = s.syscallsBeforeEnforce[key] + 1
 	}
 }
 
-func GetSyscallsCollectedBeforeEnforce() map[string]int64 {
-	return syscallsBeforeEnforce
+// SyscallsCollectedBeforeEnforce returns how often each syscall was called
+// before the policy was enforced.
+func (s *Session) SyscallsCollectedBeforeEnforce() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.syscallsBeforeEnforce)
 }
 
-func GetSyscallsCollectedAfterEnforce() map[string]int64 {
-	return syscallsAfterEnforce
+// SyscallsCollectedAfterEnforce returns how often each syscall was called
+// after the policy was enforced.
+func (s *Session) SyscallsCollectedAfterEnforce() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.syscallsAfterEnforce)
 }
@@ -1,9 +1,246 @@
 package uroot
 
 import (
-	"github.com/cuandari/lib/app/runtime"
+	"fmt"
+	"time"
+
+	"github.com/cuandari/lib/app/uroot/syscalls"
+	sec "github.com/seccomp/libseccomp-golang"
 )
 
-func allowSyscall(name string) bool {
-	return runtime.Get().SyscallsAllowMap[name]
+func (s *Session) allowSyscall(name string) bool {
+	return s.config.SyscallsAllowMap[name]
+}
+
+// isArchAllowed returns true if syscalls of the given syscall table may be
+// checked by name. Syscalls of foreign ABIs are denied unless allowed
+// explicitly.
+func (s *Session) isArchAllowed(arch sec.ScmpArch) bool {
+	return arch == nativeArch || s.config.SyscallsAllowForeignAbi
+}
+
+// syscallChecks maps syscall names to the helpers that inspect their
+// arguments. Syscalls without an entry are decided by name alone.
+var syscallChecks = map[string]func(s syscalls.Syscall, isEnter bool) bool{
+	"socket":   syscalls.IsSocketAllowed,
+	"connect":  syscalls.IsConnectAllowed,
+	"open":     syscalls.IsOpenAllowed,
+	"openat":   syscalls.IsOpenAtAllowed,
+	"openat2":  syscalls.IsOpenAt2Allowed,
+	"mkdir":    syscalls.IsMkdirAllowed,
+	"mkdirat":  syscalls.IsMkdirAtAllowed,
+	"rmdir":    syscalls.IsRmdirAllowed,
+	"unlink":   syscalls.IsUnlinkAllowed,
+	"unlinkat": syscalls.IsUnlinkAtAllowed,
+	"rename":   syscalls.IsRenameAllowed,
+	"renameat": syscalls.IsRenameAtAllowed,
+	"link":     syscalls.IsLinkAllowed,
+	"linkat":   syscalls.IsLinkAtAllowed,
+	"symlink":  syscalls.IsSymlinkAllowed,
+	// symlinkat(target, newdirfd, linkpath)
+	"symlinkat": syscalls.IsSymlinkAtAllowed,
+	// access(const char *pathname, int mode) : pathname is arg 0
+	"access": syscalls.IsAccessAllowed,
+	// faccessat(int dirfd, const char *pathname, int mode)
+	// pathname is arg 1, dirfd is arg 0
+	"faccessat": syscalls.IsFaccessAtAllowed,
+	// faccessat2(int dirfd, const char *pathname, int mode, int flags)
+	// pathname is arg 1, dirfd is arg 0
+	"faccessat2": syscalls.IsFaccessAtAllowed,
+	"write":      syscalls.IsWriteBufferAllowed,
+	"writev":     syscalls.IsWritevAllowed,
+	"send":       syscalls.IsWriteAllowed,
+	"sendmsg":    syscalls.IsSendmsgAllowed,
+	"sendmmsg":   syscalls.IsSendmmsgAllowed,
+	"sendto":     syscalls.IsSendtoAllowed,
+	"read":       syscalls.IsReadAllowed,
+	"readv":      syscalls.IsReadAllowed,
+	"recv":       syscalls.IsReadAllowed,
+	"recvfrom":   syscalls.IsReadAllowed,
+	"recvmsg":    syscalls.IsRecvmsgAllowed,
+	"recvmmsg":   syscalls.IsRecvmmsgAllowed,
+	// shutdown(int sockfd, int how)
+	"shutdown": syscalls.IsShutdownAllowed,
+	// close(int fd)
+	"close": syscalls.IsCloseAllowed,
+}
+
+// pathChecks maps syscall names to helpers that check their path arguments
+// against the allowed paths. Unlike syscallChecks, the syscall must also be
+// allowed by name.
+var pathChecks = map[string]func(s syscalls.Syscall, isEnter bool) bool{
+	"stat":              syscalls.IsStatAllowed,
+	"lstat":             syscalls.IsLstatAllowed,
+	"newfstatat":        syscalls.IsNewfstatatAllowed,
+	"statx":             syscalls.IsStatxAllowed,
+	"readlink":          syscalls.IsReadlinkAllowed,
+	"readlinkat":        syscalls.IsReadlinkAtAllowed,
+	"chdir":             syscalls.IsChdirAllowed,
+	"truncate":          syscalls.IsTruncateAllowed,
+	"chmod":             syscalls.IsChmodAllowed,
+	"fchmodat":          syscalls.IsFchmodAtAllowed,
+	"fchmodat2":         syscalls.IsFchmodAt2Allowed,
+	"chown":             syscalls.IsChownAllowed,
+	"lchown":            syscalls.IsLchownAllowed,
+	"fchownat":          syscalls.IsFchownAtAllowed,
+	"utimensat":         syscalls.IsUtimensAtAllowed,
+	"renameat2":         syscalls.IsRenameAt2Allowed,
+	"mknod":             syscalls.IsMknodAllowed,
+	"mknodat":           syscalls.IsMknodAtAllowed,
+	"getxattr":          syscalls.IsXattrAllowed,
+	"setxattr":          syscalls.IsXattrAllowed,
+	"listxattr":         syscalls.IsXattrAllowed,
+	"removexattr":       syscalls.IsXattrAllowed,
+	"lgetxattr":         syscalls.IsLxattrAllowed,
+	"lsetxattr":         syscalls.IsLxattrAllowed,
+	"llistxattr":        syscalls.IsLxattrAllowed,
+	"lremovexattr":      syscalls.IsLxattrAllowed,
+	"execve":            syscalls.IsExecveAllowed,
+	"execveat":          syscalls.IsExecveAtAllowed,
+	"inotify_add_watch": syscalls.IsInotifyAddWatchAllowed,
+}
+
+// fdChecks maps syscall names to helpers that check the types of their fd
+// arguments. Like for pathChecks, the syscall must also be allowed by name.
+var fdChecks = map[string]func(s syscalls.Syscall, isEnter bool) bool{
+	"pread64":         syscalls.IsReadAllowed,
+	"preadv":          syscalls.IsReadAllowed,
+	"preadv2":         syscalls.IsReadAllowed,
+	"pwrite64":        syscalls.IsWriteAllowed,
+	"pwritev":         syscalls.IsWriteAllowed,
+	"pwritev2":        syscalls.IsWriteAllowed,
+	"sendfile":        syscalls.IsSendfileAllowed,
+	"splice":          syscalls.IsSpliceAllowed,
+	"tee":             syscalls.IsTeeAllowed,
+	"vmsplice":        syscalls.IsVmspliceAllowed,
+	"copy_file_range": syscalls.IsCopyFileRangeAllowed,
+}
+
+// memoryChecks maps syscall names to helpers that check memory mappings
+// which write through to files. Like for pathChecks, the syscall must also be
+// allowed by name.
+var memoryChecks = map[string]func(s syscalls.Syscall, isEnter bool) bool{
+	"mmap":             syscalls.IsMmapAllowed,
+	"mprotect":         syscalls.IsMprotectAllowed,
+	"pkey_mprotect":    syscalls.IsMprotectAllowed,
+	"remap_file_pages": syscalls.IsRemapFilePagesAllowed,
+}
+
+// socketChecks maps socket syscalls, which must be allowed by name, to the
+// helpers that check the addresses they bind to.
+var socketChecks = map[string]func(s syscalls.Syscall, isEnter bool) bool{
+	"bind":   syscalls.IsBindAllowed,
+	"listen": syscalls.IsListenAllowed,
+}
+
+// nameGatedCheck returns the helper of a syscall that must be allowed by
+// name and pass the helper.
+func nameGatedCheck(name string) (func(s syscalls.Syscall, isEnter bool) bool, bool) {
+	if check, ok := pathChecks[name]; ok {
+		return check, true
+	}
+	if check, ok := fdChecks[name]; ok {
+		return check, true
+	}
+	if check, ok := socketChecks[name]; ok {
+		return check, true
+	}
+	check, ok := memoryChecks[name]
+	return check, ok
+}
+
+// fdActions names the operation of fd based syscalls for denial messages.
+var fdActions = map[string]string{
+	"write":    "write to",
+	"writev":   "write to",
+	"send":     "write to",
+	"sendmsg":  "write to",
+	"sendmmsg": "write to",
+	"sendto":   "write to",
+	"read":     "read from",
+	"readv":    "read from",
+	"recv":     "read from",
+	"recvfrom": "read from",
+	"recvmsg":  "read from",
+	"recvmmsg": "read from",
+	"pread64":  "read from",
+	"preadv":   "read from",
+	"preadv2":  "read from",
+	"pwrite64": "write to",
+	"pwritev":  "write to",
+	"pwritev2": "write to",
+	"shutdown": "shutdown",
+	"close":    "close",
+}
+
+// isInspectedSyscall returns true if the syscall's arguments are checked
+// by a helper in addition to its name.
+func isInspectedSyscall(name string) bool {
+	_, ok := syscallChecks[name]
+	if !ok {
+		_, ok = nameGatedCheck(name)
+	}
+	return ok
+}
+
+// resultChecks maps syscall names to helpers that verify the result of an
+// allowed syscall at exit, e.g. that the fd returned by open refers to an
+// allowed path.
+var resultChecks = map[string]func(s syscalls.Syscall, ret int) bool{
+	"open":    syscalls.IsOpenedFdAllowed,
+	"openat":  syscalls.IsOpenedFdAllowed,
+	"openat2": syscalls.IsOpenedFdAllowed,
+}
+
+// isSyscallResultAllowed verifies the successful result of a syscall that
+// was allowed at entry.
+func (s *Session) isSyscallResultAllowed(name string, sc syscalls.Syscall, ret int) bool {
+	check, ok := resultChecks[name]
+	if !ok {
+		return true
+	}
+
+	sc.Config = s.config
+	return check(sc, ret)
+}
+
+// isSyscallAllowed decides whether the syscall is allowed. It is shared by
+// all backends so that they apply the same policy, and records network
+// operations with the decision in the audit log.
+func (s *Session) isSyscallAllowed(name string, sc syscalls.Syscall, isEnter bool) bool {
+	sc.Audit = s.audit
+	allow := s.checkSyscall(name, sc, isEnter)
+	if s.audit != nil {
+		sc.Config = s.config
+		syscalls.AuditNetwork(sc, name, allow)
+	}
+	return allow
+}
+
+// checkSyscall applies the policy to the syscall.
+func (s *Session) checkSyscall(name string, sc syscalls.Syscall, isEnter bool) bool {
+	allow := s.allowSyscall(name)
+
+	check, ok := nameGatedCheck(name)
+	if ok && !allow {
+		return false
+	} else if !ok {
+		check, ok = syscallChecks[name]
+	}
+	if !ok {
+		return allow
+	}
+
+	sc.Config = s.config
+	sc.Stdio = s.stdio
+	sc.SessionAge = time.Since(s.started)
+	allow = check(sc, isEnter)
+
+	if action, ok := fdActions[name]; ok && !allow {
+		fd := sc.Args[0].Int()
+		fdType := sc.FdType(fd)
+		println(fmt.Sprintf("Trying to %s fd %d which is of type %s", action, fd, fdType))
+	}
+
+	return allow
 }
@@ -0,0 +1,85 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSyscallAllowedChecksPaths(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	tests := []struct {
+		name      string
+		allowName bool
+		path      string
+		want      bool
+	}{
+		{"allowed path", true, filepath.Join(allowed, "file"), true},
+		{"path outside", true, filepath.Join(outside, "file"), false},
+		{"not allowed by name", false, filepath.Join(allowed, "file"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(&runtime.Config{
+				FsConfig: runtime.FsConfig{FileSystemAllowedPaths: []string{allowed}},
+				SyscallConfig: runtime.SyscallConfig{
+					SyscallsAllowMap: map[string]bool{"chmod": tt.allowName},
+				},
+			})
+			path := []byte(tt.path + "\x00")
+			sc := syscalls.Syscall{
+				TraceePID: os.Getpid(),
+				Reader: func(addr syscalls.Addr, v interface{}) (int, error) {
+					v.(*[1]byte)[0] = path[addr]
+					return 1, nil
+				},
+			}
+
+			assert.Equal(t, tt.want, s.isSyscallAllowed("chmod", sc, true))
+			assert.True(t, isInspectedSyscall("chmod"))
+		})
+	}
+}
+
+func TestIsSyscallAllowedChecksFds(t *testing.T) {
+	fds := args.NewFdTable()
+	fds.Set(1000, args.FdInfo{Type: args.FDFile})
+	fds.Set(1001, args.FdInfo{Type: args.FDPipe})
+
+	tests := []struct {
+		name      string
+		allowName bool
+		in, out   uintptr
+		want      bool
+	}{
+		{"file to pipe", true, 1000, 1001, true},
+		{"pipe to file", true, 1001, 1000, false},
+		{"not allowed by name", false, 1000, 1001, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(&runtime.Config{
+				FsConfig: runtime.FsConfig{FileSystemAllowRead: true},
+				SyscallConfig: runtime.SyscallConfig{
+					SyscallsAllowMap: map[string]bool{"splice": tt.allowName},
+				},
+			})
+			sc := syscalls.Syscall{TraceePID: os.Getpid(), Fds: fds}
+			sc.Args[0].Value = tt.in
+			sc.Args[2].Value = tt.out
+
+			assert.Equal(t, tt.want, s.isSyscallAllowed("splice", sc, true))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package uroot
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// syscallInfo mirrors struct ptrace_syscall_info, which is returned by
+// PTRACE_GET_SYSCALL_INFO since Linux 5.3.
+type syscallInfo struct {
+	Op                 uint8
+	_                  [3]uint8
+	Arch               uint32
+	InstructionPointer uint64
+	StackPointer       uint64
+
+	// data is the union of the entry, exit and seccomp information.
+	data [8]uint64
+}
+
+// getSyscallInfo returns the syscall information of the tracee pid, which
+// must be in a syscall-stop.
+func getSyscallInfo(pid int) (*syscallInfo, error) {
+	var info syscallInfo
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_GET_SYSCALL_INFO, uintptr(pid), unsafe.Sizeof(info), uintptr(unsafe.Pointer(&info)), 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &info, nil
+}
+
+// isEntry returns true if the information describes a syscall that is
+// about to be entered. Seccomp stops are entry stops, too.
+func (i *syscallInfo) isEntry() bool {
+	return i.Op == unix.PTRACE_SYSCALL_INFO_ENTRY || i.Op == unix.PTRACE_SYSCALL_INFO_SECCOMP
+}
+
+// nr returns the syscall number of an entry stop.
+func (i *syscallInfo) nr() uint64 {
+	return i.data[0]
+}
+
+// args returns the syscall arguments of an entry stop.
+func (i *syscallInfo) args() SyscallArguments {
+	var args SyscallArguments
+	for n := range args {
+		args[n] = SyscallArgument{uintptr(i.data[n+1])}
+	}
+	return args
+}
+
+// rval returns the return value of an exit stop.
+func (i *syscallInfo) rval() int64 {
+	return int64(i.data[0])
+}
+
+// isError returns true if the syscall of an exit stop failed.
+func (i *syscallInfo) isError() bool {
+	return uint8(i.data[1]) != 0
+}
@@ -13,11 +13,14 @@ import (
 
 func TestIsAccessAllowedAbsolute(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = true
 
 	path := filepath.Join(d, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -29,12 +32,15 @@ func TestIsAccessAllowedAbsolute(t *testing.T) {
 
 func TestIsAccessNotAllowedAbsolute(t *testing.T) {
 	allowed := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{allowed}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{allowed}
+	conf.FileSystemAllowRead = true
 
 	another := t.TempDir()
 	path := filepath.Join(another, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x6000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -46,8 +52,9 @@ func TestIsAccessNotAllowedAbsolute(t *testing.T) {
 
 func TestIsFaccessAtAllowedRelativeWithDirfd(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = true
 
 	relPath := "subdir/file.txt"
 	f, err := os.Open(d)
@@ -58,6 +65,7 @@ func TestIsFaccessAtAllowedRelativeWithDirfd(t *testing.T) {
 	fd := int(f.Fd())
 
 	var s Syscall
+	s.Config = conf
 	base := uintptr(0x7000)
 	// dirfd at arg 0
 	s.Args[0] = SyscallArgument{Value: uintptr(fd)}
@@ -73,11 +81,14 @@ func TestIsFaccessAtAllowedRelativeWithDirfd(t *testing.T) {
 
 func TestIsAccessDeniedWhenReadNotAllowed(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = false
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = false
 
 	path := filepath.Join(d, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x8000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
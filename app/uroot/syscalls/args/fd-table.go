@@ -0,0 +1,126 @@
+package args
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// FdInfo describes a file descriptor of a tracee as recorded when it was
+// created.
+type FdInfo struct {
+	// Type is one of the FD* constants.
+	Type string
+	// Path is the file the fd refers to, if it was opened by path.
+	Path string
+	// Flags are the open flags, e.g. the access mode of a file.
+	Flags int
+	// Family and SockType describe sockets.
+	Family   int
+	SockType int
+	// Peer is the address an AF_INET or AF_INET6 socket was connected to.
+	Peer netip.AddrPort
+	// Sent is set once data was sent on a connected socket. Only the first
+	// data sent on a connection is inspected for the host it is meant for.
+	Sent bool
+	// CloseOnExec is set if the fd is closed by execve.
+	CloseOnExec bool
+	// Stdio is set if the fd refers to one of the standard streams the
+	// tracee was started with.
+	Stdio bool
+}
+
+// FdTable is a shadow of the file descriptor table of a traced process. It is
+// maintained by the tracer from the syscalls that create, duplicate and close
+// fds, so decisions about an fd do not need to inspect /proc. Fds that were
+// created before tracing started or by syscalls that are not tracked are
+// missing and must be looked up in /proc.
+//
+// Processes created with CLONE_FILES, e.g. threads, share the table of
+// their parent. An FdTable is not safe for concurrent use, it is only
+// accessed by the tracer goroutine.
+type FdTable struct {
+	fds map[int32]FdInfo
+}
+
+// NewFdTable returns an empty table.
+func NewFdTable() *FdTable {
+	return &FdTable{fds: make(map[int32]FdInfo)}
+}
+
+// Get returns the recorded info of fd.
+func (t *FdTable) Get(fd int32) (FdInfo, bool) {
+	info, ok := t.fds[fd]
+	return info, ok
+}
+
+// Set records fd, replacing a previous fd with the same number.
+func (t *FdTable) Set(fd int32, info FdInfo) {
+	t.fds[fd] = info
+}
+
+// Close forgets fd.
+func (t *FdTable) Close(fd int32) {
+	delete(t.fds, fd)
+}
+
+// Fds returns the numbers of the recorded fds.
+func (t *FdTable) Fds() []int32 {
+	fds := make([]int32, 0, len(t.fds))
+	for fd := range t.fds {
+		fds = append(fds, fd)
+	}
+	return fds
+}
+
+// Dup records newFd as a duplicate of oldFd. The close-on-exec flag is not
+// shared between duplicates. If oldFd is unknown, newFd is forgotten, as it
+// no longer refers to what was recorded for it.
+func (t *FdTable) Dup(oldFd int32, newFd int32, closeOnExec bool) {
+	info, ok := t.fds[oldFd]
+	if !ok {
+		delete(t.fds, newFd)
+		return
+	}
+	info.CloseOnExec = closeOnExec
+	t.fds[newFd] = info
+}
+
+// SetCloseOnExec changes the close-on-exec flag of fd.
+func (t *FdTable) SetCloseOnExec(fd int32, closeOnExec bool) {
+	if info, ok := t.fds[fd]; ok {
+		info.CloseOnExec = closeOnExec
+		t.fds[fd] = info
+	}
+}
+
+// Exec forgets the fds that are closed by a successful execve.
+func (t *FdTable) Exec() {
+	for fd, info := range t.fds {
+		if info.CloseOnExec {
+			delete(t.fds, fd)
+		}
+	}
+}
+
+// Clone returns a copy of the table for a child process created by fork.
+func (t *FdTable) Clone() *FdTable {
+	c := NewFdTable()
+	for fd, info := range t.fds {
+		c.fds[fd] = info
+	}
+	return c
+}
+
+// NewStdioFdTable returns a table with the standard streams of pid, which
+// must refer to the objects in stdio.
+func NewStdioFdTable(pid int, stdio []FileID) *FdTable {
+	t := NewFdTable()
+	for fd := int32(0); fd <= 2; fd++ {
+		id, err := FdFileID(pid, fd)
+		if err != nil || !slices.Contains(stdio, id) {
+			continue
+		}
+		t.fds[fd] = FdInfo{Type: FdType(pid, fd), Stdio: true}
+	}
+	return t
+}
@@ -0,0 +1,78 @@
+package args
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFdTableDupAndClose(t *testing.T) {
+	a := assert.New(t)
+	fds := NewFdTable()
+	fds.Set(3, FdInfo{Type: FDFile, Path: "/etc/hostname", CloseOnExec: true})
+
+	fds.Dup(3, 4, false)
+	info, ok := fds.Get(4)
+	a.True(ok)
+	a.Equal("/etc/hostname", info.Path)
+	a.False(info.CloseOnExec)
+
+	fds.Close(3)
+	_, ok = fds.Get(3)
+	a.False(ok)
+	_, ok = fds.Get(4)
+	a.True(ok)
+}
+
+func TestFdTableDupOfUnknownFdForgetsTarget(t *testing.T) {
+	a := assert.New(t)
+	fds := NewFdTable()
+	fds.Set(4, FdInfo{Type: FDPipe})
+
+	fds.Dup(3, 4, false)
+	_, ok := fds.Get(4)
+	a.False(ok)
+}
+
+func TestFdTableExec(t *testing.T) {
+	a := assert.New(t)
+	fds := NewFdTable()
+	fds.Set(3, FdInfo{Type: FDFile, CloseOnExec: true})
+	fds.Set(4, FdInfo{Type: FDSocket})
+	fds.SetCloseOnExec(4, true)
+	fds.Set(5, FdInfo{Type: FDPipe})
+
+	fds.Exec()
+	a.ElementsMatch([]int32{5}, fds.Fds())
+}
+
+func TestFdTableClone(t *testing.T) {
+	a := assert.New(t)
+	fds := NewFdTable()
+	fds.Set(3, FdInfo{Type: FDFile})
+
+	child := fds.Clone()
+	child.Close(3)
+	child.Set(4, FdInfo{Type: FDPipe})
+
+	a.ElementsMatch([]int32{3}, fds.Fds())
+	a.ElementsMatch([]int32{4}, child.Fds())
+}
+
+func TestNewStdioFdTable(t *testing.T) {
+	a := assert.New(t)
+	pid := os.Getpid()
+	stdio := StdioIDs(pid)
+
+	fds := NewStdioFdTable(pid, stdio)
+	a.Len(fds.Fds(), len(stdio))
+	for _, fd := range fds.Fds() {
+		info, _ := fds.Get(fd)
+		a.True(info.Stdio)
+		a.LessOrEqual(fd, int32(2))
+	}
+
+	// a stream that is not one of the recorded objects is left out
+	a.Empty(NewStdioFdTable(pid, nil).Fds())
+}
@@ -29,6 +29,9 @@ var (
 	stdErr = os.Stderr.Fd()
 )
 
+// IsStdIn and the related helpers compare fd numbers with the standard
+// streams of the gatekeeper. They say nothing about the object an fd of a
+// tracee refers to, see StdioIDs for that.
 func IsStdIn(fd int32) bool {
 	return fd == int32(stdIn)
 }
@@ -45,6 +48,35 @@ func IsStandardStream(fd int32) bool {
 	return IsStdIn(fd) || IsStdOut(fd) || IsStdErr(fd)
 }
 
+// FileID identifies the object an fd refers to, e.g. a file, pipe or
+// terminal.
+type FileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// FdFileID returns the identity of the object fd of pid refers to.
+func FdFileID(pid int, fd int32) (FileID, error) {
+	stat, err := LstatFd(pid, fd)
+	if err != nil {
+		return FileID{}, err
+	}
+	return FileID{Dev: uint64(stat.Dev), Ino: stat.Ino}, nil
+}
+
+// StdioIDs returns the identities of the objects behind the standard
+// streams 0, 1 and 2 of pid, e.g. the pipes it was started with or an
+// inherited terminal. Closed streams are left out.
+func StdioIDs(pid int) []FileID {
+	var ids []FileID
+	for fd := int32(0); fd <= 2; fd++ {
+		if id, err := FdFileID(pid, fd); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func LstatFd(pid int, fd int32) (unix.Stat_t, error) {
 	path := fmt.Sprintf("/proc/%d/fd/%d", pid, fd)
 	var stat unix.Stat_t
@@ -0,0 +1,259 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+
+	"github.com/cuandari/lib/app/audit"
+	"golang.org/x/sys/unix"
+)
+
+// familyNames names address families in audit entries.
+var familyNames = map[int]string{
+	unix.AF_UNIX:    "unix",
+	unix.AF_INET:    "inet",
+	unix.AF_INET6:   "inet6",
+	unix.AF_NETLINK: "netlink",
+	unix.AF_PACKET:  "packet",
+}
+
+// familyName names the address family in audit entries.
+func familyName(family int) string {
+	if name, ok := familyNames[family]; ok {
+		return name
+	}
+	return strconv.Itoa(family)
+}
+
+// protocolName names the protocol of a socket of family, type typ and
+// protocol in audit entries.
+func protocolName(family int, typ int, protocol int) string {
+	switch family {
+	case unix.AF_INET, unix.AF_INET6:
+		switch protocol {
+		case unix.IPPROTO_TCP:
+			return "tcp"
+		case unix.IPPROTO_UDP:
+			return "udp"
+		case unix.IPPROTO_ICMP:
+			return "icmp"
+		case unix.IPPROTO_ICMPV6:
+			return "icmpv6"
+		case unix.IPPROTO_SCTP:
+			return "sctp"
+		}
+	case unix.AF_NETLINK:
+		for name, p := range netlinkProtocols {
+			if p == protocol {
+				return name
+			}
+		}
+	}
+	switch typ {
+	case unix.SOCK_STREAM:
+		return "stream"
+	case unix.SOCK_DGRAM:
+		return "dgram"
+	case unix.SOCK_SEQPACKET:
+		return "seqpacket"
+	case unix.SOCK_RAW:
+		return "raw"
+	}
+	return strconv.Itoa(protocol)
+}
+
+// decision names the decision on an operation in audit entries.
+func decision(allowed bool) string {
+	if allowed {
+		return audit.Allowed
+	}
+	return audit.Denied
+}
+
+// traceeExe returns the executable of the tracee.
+func traceeExe(pid int) string {
+	exe, _ := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	return exe
+}
+
+// endpointString formats a socket address of the tracee. Unbound
+// AF_INET and AF_INET6 addresses are empty.
+func endpointString(s Syscall, sa unix.Sockaddr) string {
+	if addr, ok := inetAddrPort(sa); ok {
+		if addr.Port() == 0 && addr.Addr().Unmap().IsUnspecified() {
+			return ""
+		}
+		return addr.String()
+	}
+	if addr, ok := sa.(*unix.SockaddrUnix); ok && addr.Name != "" {
+		return traceeSocketPath(s, addr.Name)
+	}
+	return ""
+}
+
+// readEndpoint reads and formats the sockaddr of length bytes at addr of the
+// tracee. It returns the family of the sockaddr, or -1 if it cannot be read.
+func readEndpoint(s Syscall, addr Addr, length uint32, unspecAsInet bool) (int, string) {
+	family, ok := readSockaddrFamily(s, addr)
+	if !ok {
+		return -1, ""
+	}
+	switch {
+	case family == uint16(unix.AF_UNIX):
+		return unix.AF_UNIX, readUnixSockaddr(s, addr, length)
+	case family == uint16(unix.AF_UNSPEC) && unspecAsInet:
+		family = uint16(unix.AF_INET)
+		fallthrough
+	case family == uint16(unix.AF_INET) || family == uint16(unix.AF_INET6):
+		if dest, ok := ReadInetSockaddr(s.Reader, addr, length, unspecAsInet); ok {
+			return int(family), dest.String()
+		}
+	}
+	return int(family), ""
+}
+
+// socketEntry returns an audit entry describing the socket fd of the tracee:
+// its family, protocol and local address. They are looked up on a copy of
+// the socket from pidfd_getfd.
+func socketEntry(s Syscall, fd int32, event string, syscall string, allowed bool) audit.Entry {
+	e := audit.Entry{
+		PID:      s.TraceePID,
+		Exe:      traceeExe(s.TraceePID),
+		Event:    event,
+		Syscall:  syscall,
+		Decision: decision(allowed),
+	}
+
+	pidfd, err := unix.PidfdOpen(s.TraceePID, 0)
+	if err != nil {
+		return e
+	}
+	defer unix.Close(pidfd)
+	sock, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+	if err != nil {
+		return e
+	}
+	defer unix.Close(sock)
+
+	family, err := unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_DOMAIN)
+	if err != nil {
+		return e
+	}
+	typ, _ := unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_TYPE)
+	protocol, _ := unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_PROTOCOL)
+	e.Family = familyName(family)
+	e.Protocol = protocolName(family, typ, protocol)
+	if sa, err := unix.Getsockname(sock); err == nil {
+		e.Local = endpointString(s, sa)
+	}
+	return e
+}
+
+// AuditNetwork records connect, bind, and the first message to each
+// destination of sendto, sendmsg and sendmmsg in the audit log of s, with
+// the decision on the syscall. Messages on connected sockets are recorded by
+// their connect.
+func AuditNetwork(s Syscall, name string, allowed bool) {
+	if s.Audit == nil {
+		return
+	}
+	fd := s.Args[0].Int()
+
+	// fill fills in the remote or local address of e.
+	fill := func(e audit.Entry, family int, addr string) audit.Entry {
+		if e.Family == "" && family >= 0 {
+			e.Family = familyName(family)
+		}
+		if e.Event == "bind" {
+			e.Local = addr
+		} else {
+			e.Remote = addr
+		}
+		return e
+	}
+	// recordOnce records the first message to each destination. The socket
+	// is only looked up for a destination that was not recorded before, as
+	// it takes several syscalls.
+	recordOnce := func(family int, addr string) {
+		key := fmt.Sprintf("%d %d %s %s", s.TraceePID, family, addr, decision(allowed))
+		s.Audit.Once(key, func() audit.Entry {
+			return fill(socketEntry(s, fd, "send", name, allowed), family, addr)
+		})
+	}
+
+	switch name {
+	case "connect", "bind":
+		family, addr := readEndpoint(s, s.Args[1].Pointer(), s.Args[2].Uint(), false)
+		s.Audit.Record(fill(socketEntry(s, fd, name, name, allowed), family, addr))
+	case "sendto":
+		if s.Args[4].Pointer() == 0 {
+			return
+		}
+		recordOnce(readEndpoint(s, s.Args[4].Pointer(), s.Args[5].Uint(), true))
+	case "sendmsg", "sendmmsg":
+		if s.Reader == nil {
+			return
+		}
+		msgs, err := ReadMsghdrs(s.Reader, s.Args[1].Pointer(), int(s.Args[2].Uint()), name == "sendmmsg")
+		if err != nil {
+			return
+		}
+		for _, hdr := range msgs {
+			if hdr.Name != 0 {
+				recordOnce(readEndpoint(s, Addr(hdr.Name), hdr.Namelen, true))
+			}
+		}
+	}
+}
+
+// AuditAccept records the connection fd that accept or accept4 of s
+// returned, with the address of the peer.
+func AuditAccept(s Syscall, name string, fd int32) {
+	if s.Audit == nil {
+		return
+	}
+	e := socketEntry(s, fd, "accept", name, true)
+
+	// The kernel wrote the address of the peer and its length to
+	// accept(sockfd, addr, addrlen).
+	var length uint32
+	if s.Args[1].Pointer() != 0 && s.Reader != nil {
+		if _, err := s.Reader(s.Args[2].Pointer(), &length); err == nil {
+			_, e.Remote = readEndpoint(s, s.Args[1].Pointer(), length, false)
+		}
+	}
+	if e.Remote == "" {
+		if sa, err := traceeSockaddr(s, fd, unix.Getpeername); err == nil {
+			e.Remote = endpointString(s, sa)
+		}
+	}
+	s.Audit.Record(e)
+}
+
+// auditHost records the host that data sent by the tracee to dest is for,
+// e.g. the name of a DNS query.
+func auditHost(s Syscall, event string, protocol string, dest netip.AddrPort, host string, decision string) {
+	if s.Audit == nil {
+		return
+	}
+	e := audit.Entry{
+		PID:      s.TraceePID,
+		Exe:      traceeExe(s.TraceePID),
+		Event:    event,
+		Protocol: protocol,
+		Host:     host,
+		Decision: decision,
+	}
+	if dest.IsValid() {
+		e.Remote = dest.String()
+		e.Family = familyName(unix.AF_INET)
+		if dest.Addr().Is6() {
+			e.Family = familyName(unix.AF_INET6)
+		}
+	}
+	s.Audit.Record(e)
+}
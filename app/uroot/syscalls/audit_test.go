@@ -0,0 +1,119 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/runtime"
+	"golang.org/x/sys/unix"
+)
+
+// auditEntries decodes the entries written to b.
+func auditEntries(t *testing.T, b *bytes.Buffer) []audit.Entry {
+	var entries []audit.Entry
+	s := bufio.NewScanner(b)
+	for s.Scan() {
+		var e audit.Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditNetwork(t *testing.T) {
+	const nameAddr, lenAddr = 0x2000, 0x3000
+	remote := encode(t, unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: [4]byte{192, 0, 2, 1}})
+	binary.BigEndian.PutUint16(remote[2:4], 53)
+
+	sock, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(sock)
+
+	var b bytes.Buffer
+	s := Syscall{
+		TraceePID: os.Getpid(),
+		Reader:    fakeMemory{nameAddr: remote, lenAddr: encode(t, uint32(len(remote)))}.read,
+		Config:    &runtime.Config{},
+		Audit:     audit.New(&b),
+	}
+	s.Args[0] = SyscallArgument{Value: uintptr(sock)}
+
+	connect := s
+	connect.Args[1] = SyscallArgument{Value: nameAddr}
+	connect.Args[2] = SyscallArgument{Value: uintptr(len(remote))}
+	AuditNetwork(connect, "connect", false)
+
+	// Only the first message to a destination is recorded.
+	sendto := s
+	sendto.Args[4] = SyscallArgument{Value: nameAddr}
+	sendto.Args[5] = SyscallArgument{Value: uintptr(len(remote))}
+	AuditNetwork(sendto, "sendto", true)
+	AuditNetwork(sendto, "sendto", true)
+	AuditNetwork(s, "sendto", true)
+
+	accept := s
+	accept.Args[1] = SyscallArgument{Value: nameAddr}
+	accept.Args[2] = SyscallArgument{Value: lenAddr}
+	AuditAccept(accept, "accept4", int32(sock))
+
+	entries := auditEntries(t, &b)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	want := []struct{ event, syscall, decision string }{
+		{"connect", "connect", audit.Denied},
+		{"send", "sendto", audit.Allowed},
+		{"accept", "accept4", audit.Allowed},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Event != w.event || e.Syscall != w.syscall || e.Decision != w.decision {
+			t.Fatalf("expected a %s entry of %s %s, got %+v", w.event, w.syscall, w.decision, e)
+		}
+		if e.PID != os.Getpid() || e.Exe == "" || e.Family != "inet" || e.Protocol != "udp" || e.Remote != "192.0.2.1:53" {
+			t.Fatalf("unexpected entry %+v", e)
+		}
+	}
+}
+
+func TestAuditBindUnix(t *testing.T) {
+	const nameAddr = 0x2000
+	name := encode(t, unix.RawSockaddrUnix{Family: unix.AF_UNIX, Path: [108]int8{'/', 's'}})
+
+	sock, err := unix.Socket(unix.AF_UNIX, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(sock)
+
+	var b bytes.Buffer
+	s := Syscall{
+		TraceePID: os.Getpid(),
+		Reader:    fakeMemory{nameAddr: name}.read,
+		Config:    &runtime.Config{},
+		Audit:     audit.New(&b),
+	}
+	s.Args[0] = SyscallArgument{Value: uintptr(sock)}
+	s.Args[1] = SyscallArgument{Value: nameAddr}
+	s.Args[2] = SyscallArgument{Value: uintptr(len(name))}
+	AuditNetwork(s, "bind", true)
+
+	entries := auditEntries(t, &b)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %+v", entries)
+	}
+	if e := entries[0]; e.Event != "bind" || e.Family != "unix" || e.Protocol != "stream" || e.Local != "/s" || e.Remote != "" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+	"net/netip"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsBindAllowed checks bind(sockfd, addr, addrlen). With loopback permission
+// but without server permission, AF_INET and AF_INET6 sockets must be bound
+// to a loopback address, so they cannot be reached from other hosts. Client
+// permission does not lift this, as clients do not need to be reachable.
+func IsBindAllowed(s Syscall, isEnter bool) bool {
+	c := s.config()
+	if !c.NetworkAllowLoopback || c.NetworkAllowServer {
+		return true
+	}
+	family, ok := readSockaddrFamily(s, s.Args[1].Pointer())
+	if !ok {
+		return false
+	}
+	if family != uint16(unix.AF_INET) && family != uint16(unix.AF_INET6) {
+		return true
+	}
+
+	addr, ok := ReadInetSockaddr(s.Reader, s.Args[1].Pointer(), s.Args[2].Uint(), false)
+	if !ok || !addr.Addr().Unmap().IsLoopback() {
+		fmt.Printf("binding to %s is not allowed, only loopback addresses are\n", addr)
+		return false
+	}
+	return true
+}
+
+// IsListenAllowed checks listen(sockfd, backlog). With loopback permission
+// but without server permission, AF_INET and AF_INET6 sockets must be bound
+// to a loopback address. listen binds unbound sockets to the unspecified
+// address, which is refused, too.
+func IsListenAllowed(s Syscall, isEnter bool) bool {
+	c := s.config()
+	if !c.NetworkAllowLoopback || c.NetworkAllowServer {
+		return true
+	}
+	sa, err := traceeSockaddr(s, s.Args[0].Int(), unix.Getsockname)
+	if err != nil {
+		return false
+	}
+	addr, ok := inetAddrPort(sa)
+	if ok && !addr.Addr().Unmap().IsLoopback() {
+		fmt.Printf("listening on %s is not allowed, only loopback addresses are\n", addr)
+		return false
+	}
+	return true
+}
+
+// isLoopbackBound makes sure that sending to the loopback address dest does
+// not make socket fd of the tracee reachable from other hosts. With loopback
+// permission but without server permission, an unbound socket is bound to
+// a loopback address first. The kernel would otherwise bind a datagram
+// socket to the unspecified address when sending without connect. The bind
+// is made on a duplicate from pidfd_getfd, which shares the socket.
+func isLoopbackBound(s Syscall, fd int32, dest netip.AddrPort) bool {
+	c := s.config()
+	if !c.NetworkAllowLoopback || c.NetworkAllowServer || !isLoopback(dest.Addr()) {
+		return true
+	}
+
+	pidfd, err := unix.PidfdOpen(s.TraceePID, 0)
+	if err != nil {
+		fmt.Printf("unable to open pid %d to bind socket %d: %s\n", s.TraceePID, fd, err.Error())
+		return false
+	}
+	defer unix.Close(pidfd)
+	sock, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+	if err != nil {
+		fmt.Printf("unable to get socket %d of pid %d: %s\n", fd, s.TraceePID, err.Error())
+		return false
+	}
+	defer unix.Close(sock)
+
+	// Connecting binds to the source address of the route, which is a
+	// loopback address for loopback destinations.
+	typ, err := unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_TYPE)
+	if err != nil {
+		return false
+	}
+	if typ != unix.SOCK_DGRAM {
+		return true
+	}
+	sa, err := unix.Getsockname(sock)
+	if err != nil {
+		return false
+	}
+	var loopback unix.Sockaddr
+	switch addr := sa.(type) {
+	case *unix.SockaddrInet4:
+		if addr.Port != 0 {
+			return true
+		}
+		loopback = &unix.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}
+	case *unix.SockaddrInet6:
+		if addr.Port != 0 {
+			return true
+		}
+		if dest.Addr().Is6() && !dest.Addr().Is4In6() {
+			loopback = &unix.SockaddrInet6{Addr: netip.IPv6Loopback().As16()}
+		} else {
+			// IPv4 destinations of dual-stack sockets need an IPv4
+			// source address.
+			loopback = &unix.SockaddrInet6{Addr: netip.AddrFrom4([4]byte{127, 0, 0, 1}).As16()}
+		}
+	default:
+		return true
+	}
+	if err := unix.Bind(sock, loopback); err != nil {
+		fmt.Printf("unable to bind socket %d of pid %d to loopback: %s\n", fd, s.TraceePID, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+// listeningSocket returns a TCP socket of this process bound to addr.
+func listeningSocket(t *testing.T, addr [4]byte) int {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, &unix.SockaddrInet4{Addr: addr}); err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	const nameAddr = 0x2000
+	inet := func(addr [4]byte) []byte {
+		b := encode(t, unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: addr})
+		binary.BigEndian.PutUint16(b[2:4], 8080)
+		return b
+	}
+	loopback := inet([4]byte{127, 0, 0, 1})
+	unspecified := inet([4]byte{})
+	remote := inet([4]byte{192, 0, 2, 1})
+	loopback6 := encode(t, unix.RawSockaddrInet6{Family: unix.AF_INET6, Addr: [16]byte{15: 1}})
+	local := encode(t, unix.RawSockaddrUnix{Family: unix.AF_UNIX})
+
+	withAddr := func(check func(Syscall, bool) bool, addrArg int, name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Reader = fakeMemory{nameAddr: name}.read
+			s.Args[addrArg] = SyscallArgument{Value: nameAddr}
+			s.Args[addrArg+1] = SyscallArgument{Value: uintptr(len(name))}
+			return check(*s, true)
+		}
+	}
+	socket := func(domain int, typ int) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[0] = SyscallArgument{Value: uintptr(domain)}
+			s.Args[1] = SyscallArgument{Value: uintptr(typ)}
+			return IsSocketAllowed(*s, true)
+		}
+	}
+	listen := func(fd int) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[0] = SyscallArgument{Value: uintptr(fd)}
+			return IsListenAllowed(*s, true)
+		}
+	}
+	sendto := func(fd int, name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[0] = SyscallArgument{Value: uintptr(fd)}
+			return withAddr(IsSendtoAllowed, 4, name)(s)
+		}
+	}
+	udp, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(udp)
+	loopbackListener := listeningSocket(t, [4]byte{127, 0, 0, 1})
+	anyListener := listeningSocket(t, [4]byte{})
+
+	loopbackOnly := runtime.NetworkConfig{NetworkAllowLoopback: true, LocalSocketsAllow: true}
+	server := runtime.NetworkConfig{NetworkAllowLoopback: true, NetworkAllowServer: true}
+	tests := []struct {
+		name    string
+		check   func(s *Syscall) bool
+		network runtime.NetworkConfig
+		want    bool
+	}{
+		{"tcp socket", socket(unix.AF_INET6, unix.SOCK_STREAM), loopbackOnly, true},
+		{"raw socket", socket(unix.AF_INET, unix.SOCK_RAW), runtime.NetworkConfig{NetworkAllowLoopback: true, NetworkAllowRawSockets: true}, false},
+		{"connect loopback", withAddr(IsConnectAllowed, 1, loopback), loopbackOnly, true},
+		{"connect loopback6", withAddr(IsConnectAllowed, 1, loopback6), loopbackOnly, true},
+		{"connect remote", withAddr(IsConnectAllowed, 1, remote), loopbackOnly, false},
+		{"connect remote with client", withAddr(IsConnectAllowed, 1, remote), runtime.NetworkConfig{NetworkAllowLoopback: true, NetworkAllowClient: true}, true},
+		{"sendto loopback", sendto(udp, loopback), loopbackOnly, true},
+		{"sendto remote", withAddr(IsSendtoAllowed, 4, remote), loopbackOnly, false},
+		{"bind loopback", withAddr(IsBindAllowed, 1, loopback), loopbackOnly, true},
+		{"bind any", withAddr(IsBindAllowed, 1, unspecified), loopbackOnly, false},
+		{"bind any with server", withAddr(IsBindAllowed, 1, unspecified), server, true},
+		{"bind any with client", withAddr(IsBindAllowed, 1, unspecified), runtime.NetworkConfig{NetworkAllowLoopback: true, NetworkAllowClient: true}, false},
+		{"bind unix", withAddr(IsBindAllowed, 1, local), loopbackOnly, true},
+		{"listen loopback", listen(loopbackListener), loopbackOnly, true},
+		{"listen any", listen(anyListener), loopbackOnly, false},
+		{"listen any with server", listen(anyListener), server, true},
+	}
+
+	fds := args.NewFdTable()
+	fds.Set(1000, args.FdInfo{Type: args.FDSocket, Family: unix.AF_INET, SockType: unix.SOCK_DGRAM})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{NetworkConfig: tt.network},
+			}
+			s.Args[0] = SyscallArgument{Value: 1000}
+
+			if got := tt.check(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Sending would have bound the socket to the unspecified address.
+	sa, err := unix.Getsockname(udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := sa.(*unix.SockaddrInet4); !ok || addr.Addr != [4]byte{127, 0, 0, 1} || addr.Port == 0 {
+		t.Fatalf("expected the socket to be bound to loopback, got %+v", sa)
+	}
+}
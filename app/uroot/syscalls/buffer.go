@@ -0,0 +1,73 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// maxIovecs bounds the iovecs read from the tracee to inspect sent data. It
+// is UIO_MAXIOV, the most iovecs the kernel accepts, so that all of the data
+// can be read.
+const maxIovecs = 1024
+
+// sentData reads up to limit bytes of the data a syscall sends from the
+// tracee. It is nil if the kernel moves the data without it passing through
+// the tracee's memory, e.g. for sendfile.
+type sentData func(limit int) []byte
+
+// read returns up to limit bytes of the data, or nil.
+func (d sentData) read(limit int) []byte {
+	if d == nil {
+		return nil
+	}
+	return d(limit)
+}
+
+// bufferData is the data of the buffer of length bytes at addr.
+func bufferData(s Syscall, addr Addr, length uint64) sentData {
+	return func(limit int) []byte {
+		return readBuffer(s, addr, min(length, uint64(limit)))
+	}
+}
+
+// iovecData is the data described by the iovcnt iovecs at addr.
+func iovecData(s Syscall, addr Addr, iovcnt uint64) sentData {
+	return func(limit int) []byte {
+		return readIovecs(s, addr, iovcnt, limit)
+	}
+}
+
+// readBuffer reads the buffer of length bytes at addr of the tracee.
+func readBuffer(s Syscall, addr Addr, length uint64) []byte {
+	if addr == 0 || s.Reader == nil {
+		return nil
+	}
+	b := make([]byte, length)
+	if _, err := s.Reader(addr, b); err != nil {
+		return nil
+	}
+	return b
+}
+
+// iovec is struct iovec of the 64-bit ABIs.
+type iovec struct {
+	Base uint64
+	Len  uint64
+}
+
+// readIovecs reads up to limit bytes of the data described by the iovcnt
+// iovecs at addr of the tracee.
+func readIovecs(s Syscall, addr Addr, iovcnt uint64, limit int) []byte {
+	if addr == 0 || s.Reader == nil || iovcnt == 0 {
+		return nil
+	}
+	iovs := make([]iovec, min(iovcnt, maxIovecs))
+	if _, err := s.Reader(addr, iovs); err != nil {
+		return nil
+	}
+	var data []byte
+	for _, iov := range iovs {
+		if len(data) >= limit {
+			break
+		}
+		data = append(data, readBuffer(s, Addr(iov.Base), min(iov.Len, uint64(limit-len(data))))...)
+	}
+	return data
+}
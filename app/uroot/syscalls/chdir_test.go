@@ -0,0 +1,17 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"testing"
+)
+
+func TestIsChdirAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsChdirAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: allowed}, want: true},
+		{name: "outside", paths: map[int]string{0: outside}, want: false},
+		{name: "dotdot out of allowed", paths: map[int]string{0: allowed + "/.."}, want: false},
+	})
+}
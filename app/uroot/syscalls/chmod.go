@@ -0,0 +1,22 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsChmodAllowed checks chmod(pathname, mode).
+// pathname is arg 0.
+func IsChmodAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowed(s, 0, -1)
+}
+
+// IsFchmodAtAllowed checks fchmodat(dirfd, pathname, mode).
+// pathname is arg 1, dirfd is arg 0. fchmodat has no flags argument and
+// always follows symlinks, whatever the tracee left in the 4th register.
+func IsFchmodAtAllowed(s Syscall, isEnter bool) bool {
+	return PathAtIsAllowed(s, 1, 0, 0)
+}
+
+// IsFchmodAt2Allowed checks fchmodat2(dirfd, pathname, mode, flags).
+// pathname is arg 1, dirfd is arg 0, flags is arg 3.
+func IsFchmodAt2Allowed(s Syscall, isEnter bool) bool {
+	return PathAtIsAllowed(s, 1, 0, int(s.Args[3].Int()))
+}
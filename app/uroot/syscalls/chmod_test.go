@@ -0,0 +1,42 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsChmodAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsChmodAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: filepath.Join(allowed, "file")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+		{name: "symlink to outside", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: false},
+	})
+}
+
+func TestIsFchmodAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsFchmodAtAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink to outside", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 3: unix.AT_SYMLINK_NOFOLLOW}, want: false},
+	})
+}
+
+func TestIsFchmodAt2Allowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsFchmodAt2Allowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink to outside", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 3: unix.AT_SYMLINK_NOFOLLOW}, want: true},
+	})
+}
@@ -0,0 +1,22 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsChownAllowed checks chown(pathname, owner, group).
+// pathname is arg 0.
+func IsChownAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowed(s, 0, -1)
+}
+
+// IsLchownAllowed checks lchown(pathname, owner, group), which does not
+// follow a symlink in the last component.
+// pathname is arg 0.
+func IsLchownAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 0, -1)
+}
+
+// IsFchownAtAllowed checks fchownat(dirfd, pathname, owner, group, flags).
+// pathname is arg 1, dirfd is arg 0.
+func IsFchownAtAllowed(s Syscall, isEnter bool) bool {
+	return PathAtIsAllowed(s, 1, 0, int(s.Args[4].Int()))
+}
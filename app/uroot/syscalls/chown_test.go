@@ -0,0 +1,40 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsChownAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsChownAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: filepath.Join(allowed, "file")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+		{name: "symlink to outside", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: false},
+	})
+}
+
+func TestIsLchownAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsLchownAllowed, []pathSyscallTest{
+		{name: "symlink itself", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+	})
+}
+
+func TestIsFchownAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsFchownAtAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 4: unix.AT_SYMLINK_NOFOLLOW}, want: true},
+		{name: "empty path of fd", paths: map[int]string{1: ""}, ints: map[int]uintptr{0: 3, 4: unix.AT_EMPTY_PATH}, want: true},
+	})
+}
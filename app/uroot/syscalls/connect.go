@@ -4,37 +4,107 @@ package syscalls
 
 import (
 	"fmt"
+	"net/netip"
 
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
+// readSockaddrFamily reads the family of the sockaddr at addr from the
+// tracee.
+func readSockaddrFamily(s Syscall, addr Addr) (uint16, bool) {
+	var family uint16
+	if addr == 0 || s.Reader == nil {
+		return 0, false
+	}
+	if _, err := s.Reader(addr, &family); err != nil {
+		return 0, false
+	}
+	return family, true
+}
+
+// isDestinationFamilyAllowed decides whether the tracee may reach a peer of
+// the given address family, by connecting to it or by sending to it.
+func isDestinationFamilyAllowed(s Syscall, family uint16) bool {
+	if family == uint16(unix.AF_UNIX) || family == uint16(unix.AF_NETLINK) {
+		fmt.Println("connect family:", family, "connect to local socket", s.config().LocalSocketsAllow)
+		return s.config().LocalSocketsAllow
+	}
+
+	if family == uint16(unix.AF_INET) || family == uint16(unix.AF_INET6) {
+		// connect() is a client operation; require client permission, or
+		// loopback permission which isInetDestinationAllowed restricts
+		// to loopback addresses.
+		allowed := s.config().NetworkAllowClient || s.config().NetworkAllowLoopback
+		fmt.Println("connect family", family, "connect to remote socket", allowed)
+		return allowed
+	}
+	if family == uint16(unix.AF_PACKET) {
+		fmt.Println("connect family", family, "connect to remote socket", s.config().NetworkAllowClient)
+		return s.config().NetworkAllowClient
+	}
+
+	return false
+}
+
 // IsConnectAllowed determines allowance for connect using the sockaddr family
 // decoded directly from the tracee via the Syscall.Reader.
 func IsConnectAllowed(s Syscall, isEnter bool) bool {
-	addr := s.Args[1].Pointer()
-	var family uint16
-	if addr != 0 && s.Reader != nil {
-		if _, err := s.Reader(addr, &family); err == nil {
-			if family == uint16(unix.AF_UNIX) || family == uint16(unix.AF_NETLINK) {
-				fmt.Println("connect family:", family, "connect to local socket", runtime.Get().LocalSocketsAllow)
-				return runtime.Get().LocalSocketsAllow
-			}
-
-			if family == uint16(unix.AF_INET) || family == uint16(unix.AF_INET6) || family == uint16(unix.AF_PACKET) {
-				// connect() is a client operation; require client permission
-				fmt.Println("connect family", family, "connect to remote socket", runtime.Get().NetworkAllowClient)
-				return runtime.Get().NetworkAllowClient
-			}
-
-			if family == uint16(unix.AF_UNSPEC) {
-				// AF_UNSPEC connect on datagram sockets can “disconnect”; allow only if at least
-				// local sockets or network client capability is enabled.
-				fmt.Println("connect family", family, "connect to unspeced socket", runtime.Get().LocalSocketsAllow || runtime.Get().NetworkAllowClient)
-				return runtime.Get().LocalSocketsAllow || runtime.Get().NetworkAllowClient
-			}
-		}
+	family, ok := readSockaddrFamily(s, s.Args[1].Pointer())
+	if !ok {
+		return false
 	}
 
-	return false
+	if family == uint16(unix.AF_UNSPEC) {
+		// AF_UNSPEC connect on datagram sockets can “disconnect”; allow only if at least
+		// local sockets or network client capability is enabled.
+		allowed := s.config().LocalSocketsAllow || s.config().NetworkAllowClient || s.config().NetworkAllowLoopback
+		fmt.Println("connect family", family, "connect to unspeced socket", allowed)
+		return allowed
+	}
+
+	if !isDestinationFamilyAllowed(s, family) {
+		return false
+	}
+	if family == uint16(unix.AF_INET) || family == uint16(unix.AF_INET6) {
+		dest, _ := ReadInetSockaddr(s.Reader, s.Args[1].Pointer(), s.Args[2].Uint(), false)
+		return isInetDestinationAllowed(s, dest)
+	}
+	return true
+}
+
+// isLoopback returns true if addr refers to the local host. Connecting or
+// sending to the unspecified address reaches the local host, too.
+func isLoopback(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsUnspecified()
+}
+
+// isInetDestinationAllowed decides whether the tracee may reach the AF_INET
+// or AF_INET6 address dest. With loopback permission only, dest must be a
+// loopback address.
+func isInetDestinationAllowed(s Syscall, dest netip.AddrPort) bool {
+	if !s.config().NetworkAllowClient && !isLoopback(dest.Addr()) {
+		fmt.Printf("reaching %s is not allowed, only loopback addresses are\n", dest)
+		return false
+	}
+	return isEgressProxyDestination(s, dest)
+}
+
+// isEgressProxyDestination decides whether the tracee may reach the AF_INET
+// or AF_INET6 address dest. With an egress proxy, the only address it may
+// reach is the proxy, so all of its network traffic is routed through it.
+func isEgressProxyDestination(s Syscall, dest netip.AddrPort) bool {
+	proxy := s.config().NetworkEgressProxy
+	if proxy == "" {
+		return true
+	}
+	p, err := netip.ParseAddrPort(proxy)
+	if err != nil {
+		return false
+	}
+	if dest.Addr().Unmap() != p.Addr().Unmap() || dest.Port() != p.Port() {
+		fmt.Printf("reaching %s is not allowed, it bypasses the egress proxy %s\n", dest, proxy)
+		return false
+	}
+	return true
 }
@@ -0,0 +1,185 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"github.com/cuandari/lib/app/utils"
+	"golang.org/x/sys/unix"
+)
+
+// dnsPort is the port DNS servers are queried on.
+const dnsPort = 53
+
+// maxDNSQueryLen bounds the data read from the tracee to find the question
+// of a DNS query sent in a datagram: a header and a name of at most 255
+// bytes fit well within it.
+const maxDNSQueryLen = 512
+
+// maxDNSStreamLen bounds the data of a write to a DNS server over TCP. All of
+// it is inspected, as it can hold several queries. Larger writes are refused.
+const maxDNSStreamLen = 1 << 20
+
+var errNotDNSQuery = errors.New("not a DNS query")
+
+// parseDNSQuestions returns the names asked for by the DNS query msg, in
+// lower case and without the trailing dot.
+func parseDNSQuestions(msg []byte) ([]string, error) {
+	if len(msg) < 12 {
+		return nil, errNotDNSQuery
+	}
+	// The QR bit is set for responses.
+	if msg[2]&0x80 != 0 {
+		return nil, errNotDNSQuery
+	}
+	count := int(binary.BigEndian.Uint16(msg[4:6]))
+	if count == 0 {
+		return nil, errNotDNSQuery
+	}
+
+	var names []string
+	off := 12
+	for range count {
+		var labels []string
+		for {
+			if off >= len(msg) {
+				return nil, errNotDNSQuery
+			}
+			n := int(msg[off])
+			off++
+			if n == 0 {
+				break
+			}
+			// Queries do not compress names, pointers are refused
+			// rather than followed.
+			if n&0xc0 != 0 || off+n > len(msg) {
+				return nil, errNotDNSQuery
+			}
+			labels = append(labels, strings.ToLower(string(msg[off:off+n])))
+			off += n
+		}
+		// QTYPE and QCLASS
+		off += 4
+		if off > len(msg) {
+			return nil, errNotDNSQuery
+		}
+		names = append(names, strings.Join(labels, "."))
+	}
+	return names, nil
+}
+
+// parseDNSStream returns the names asked for by the DNS queries in data
+// written to a TCP connection. Each query is prefixed with its length, and
+// resolvers answer several queries sent at once, so every query is parsed.
+// Data that ends within a query is refused, as the rest of it would be sent
+// uninspected by the next write.
+func parseDNSStream(data []byte) ([]string, error) {
+	if len(data) == 0 {
+		return nil, errNotDNSQuery
+	}
+	var names []string
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errNotDNSQuery
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, errNotDNSQuery
+		}
+		questions, err := parseDNSQuestions(data[2 : 2+n])
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, questions...)
+		data = data[2+n:]
+	}
+	return names, nil
+}
+
+// isStreamSocket returns true if fd of the tracee is a stream socket. Sockets
+// missing in the shadow fd table are looked up on a duplicate from
+// pidfd_getfd.
+func isStreamSocket(s Syscall, fd int32) bool {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok && info.SockType != 0 {
+			return info.SockType == unix.SOCK_STREAM
+		}
+	}
+	typ := -1
+	_, err := traceeSockaddr(s, fd, func(sock int) (unix.Sockaddr, error) {
+		var err error
+		typ, err = unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_TYPE)
+		return nil, err
+	})
+	return err == nil && typ == unix.SOCK_STREAM
+}
+
+// readDNSQuestions returns the names asked for by the data sent on fd, which
+// is a datagram with one query, or a write to a TCP connection with any
+// number of length-prefixed queries.
+func readDNSQuestions(s Syscall, fd int32, data sentData) ([]string, error) {
+	if !isStreamSocket(s, fd) {
+		return parseDNSQuestions(data.read(maxDNSQueryLen))
+	}
+	msg := data.read(maxDNSStreamLen + 1)
+	if len(msg) > maxDNSStreamLen {
+		return nil, errNotDNSQuery
+	}
+	return parseDNSStream(msg)
+}
+
+// isDNSQueryAllowed inspects data sent on socket fd of the tracee if it goes
+// to port 53. dest is the destination of the message if given, otherwise the
+// peer of the socket. The question names must be within the allowed domains,
+// as policies on the resolved IPs break for CDNs. Data to port 53 that is not
+// a DNS query is refused, too. Every name queried is logged and recorded in
+// the audit log for egress reviews, also without allowed domains.
+func isDNSQueryAllowed(s Syscall, fd int32, dest netip.AddrPort, data sentData) bool {
+	c := s.config()
+	enforce := len(c.NetworkAllowedDomains) > 0
+	if (!enforce && s.Audit == nil) || s.FdType(fd) != args.FDSocket {
+		return true
+	}
+	if !dest.IsValid() {
+		var ok bool
+		if dest, ok = socketPeer(s, fd); !ok {
+			return true
+		}
+	}
+	if dest.Port() != dnsPort {
+		return true
+	}
+
+	names, err := readDNSQuestions(s, fd, data)
+	if err != nil {
+		if !enforce {
+			return true
+		}
+		fmt.Printf("data sent by pid %d to %s is not a DNS query\n", s.TraceePID, dest)
+		return c.NetworkLogDeniedDomains
+	}
+
+	allowed := true
+	for _, name := range names {
+		if !enforce || utils.MatchDomain(c.NetworkAllowedDomains, name) {
+			fmt.Printf("DNS query of pid %d to %s for %s\n", s.TraceePID, dest, name)
+			auditHost(s, "dns", "dns", dest, name, audit.Allowed)
+			continue
+		}
+		fmt.Printf("DNS query of pid %d to %s for %s is not allowed\n", s.TraceePID, dest, name)
+		if c.NetworkLogDeniedDomains {
+			auditHost(s, "dns", "dns", dest, name, audit.Logged)
+		} else {
+			auditHost(s, "dns", "dns", dest, name, audit.Denied)
+		}
+		allowed = false
+	}
+	return allowed || c.NetworkLogDeniedDomains
+}
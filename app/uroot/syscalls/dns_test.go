@@ -0,0 +1,192 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+// dnsQuery returns a DNS query for the A records of name.
+func dnsQuery(name string) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0, 0, 1, 0, 1)
+}
+
+// tcpQuery prefixes the DNS query msg with its length, as sent over TCP.
+func tcpQuery(msg []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...)
+}
+
+func TestParseDNSStream(t *testing.T) {
+	names, err := parseDNSStream(append(tcpQuery(dnsQuery("example.com")), tcpQuery(dnsQuery("evil.test"))...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"example.com", "evil.test"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected %v got %v", exp, names)
+	}
+
+	query := tcpQuery(dnsQuery("example.com"))
+	trailing := append(tcpQuery(dnsQuery("example.com")), 0)
+	for _, data := range [][]byte{nil, query[:1], query[:len(query)-1], trailing, dnsQuery("example.com")} {
+		if _, err := parseDNSStream(data); err == nil {
+			t.Fatalf("expected %q not to parse", data)
+		}
+	}
+}
+
+func TestParseDNSQuestions(t *testing.T) {
+	names, err := parseDNSQuestions(dnsQuery("Files.Example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"files.example.com"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected %v got %v", exp, names)
+	}
+
+	response := dnsQuery("example.com")
+	response[2] |= 0x80
+	truncated := dnsQuery("example.com")
+	truncated = truncated[:len(truncated)-3]
+	compressed := append(dnsQuery("example.com")[:12], 0xc0, 12, 0, 1, 0, 1)
+	for _, msg := range [][]byte{response, truncated, compressed, []byte("GET / HTTP/1.1\r\n")} {
+		if _, err := parseDNSQuestions(msg); err == nil {
+			t.Fatalf("expected %q not to parse", msg)
+		}
+	}
+}
+
+func TestDNSQueryAllowed(t *testing.T) {
+	const (
+		udp      = 1000
+		tcp      = 1001
+		web      = 1002
+		bufAddr  = 0x1000
+		nameAddr = 0x2000
+		iovAddr  = 0x3000
+	)
+	fds := args.NewFdTable()
+	resolver := netip.MustParseAddrPort("192.0.2.53:53")
+	fds.Set(udp, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_DGRAM})
+	fds.Set(tcp, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_STREAM, Peer: resolver})
+	fds.Set(web, args.FdInfo{Type: args.FDSocket, Peer: netip.MustParseAddrPort("192.0.2.80:443")})
+
+	dest := encode(t, unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: [4]byte{192, 0, 2, 53}})
+	binary.BigEndian.PutUint16(dest[2:4], 53)
+
+	sendto := func(query []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Reader = fakeMemory{bufAddr: query, nameAddr: dest}.read
+			s.Args[0] = SyscallArgument{Value: udp}
+			s.Args[1] = SyscallArgument{Value: bufAddr}
+			s.Args[2] = SyscallArgument{Value: uintptr(len(query))}
+			s.Args[4] = SyscallArgument{Value: nameAddr}
+			s.Args[5] = SyscallArgument{Value: uintptr(len(dest))}
+			return IsSendtoAllowed(*s, true)
+		}
+	}
+	writev := func(fd int, query []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			prefix := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+			iovs := encode(t, []iovec{{Base: nameAddr, Len: 2}, {Base: bufAddr, Len: uint64(len(query))}})
+			s.Reader = fakeMemory{bufAddr: query, nameAddr: prefix, iovAddr: iovs}.read
+			s.Args[0] = SyscallArgument{Value: uintptr(fd)}
+			s.Args[1] = SyscallArgument{Value: iovAddr}
+			s.Args[2] = SyscallArgument{Value: 2}
+			return IsWritevAllowed(*s, true)
+		}
+	}
+
+	// write sends data as is on the TCP socket.
+	write := func(data []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Reader = fakeMemory{bufAddr: data}.read
+			s.Args[0] = SyscallArgument{Value: tcp}
+			s.Args[1] = SyscallArgument{Value: bufAddr}
+			s.Args[2] = SyscallArgument{Value: uintptr(len(data))}
+			return IsWriteBufferAllowed(*s, true)
+		}
+	}
+	pipelined := append(tcpQuery(dnsQuery("example.com")), tcpQuery(dnsQuery("evil.test"))...)
+	partial := tcpQuery(dnsQuery("example.com"))
+	partial = partial[:len(partial)-1]
+
+	client := runtime.NetworkConfig{NetworkAllowClient: true}
+	domains := runtime.NetworkConfig{NetworkAllowClient: true, NetworkAllowedDomains: []string{"example.com"}}
+	logged := runtime.NetworkConfig{NetworkAllowClient: true, NetworkAllowedDomains: []string{"example.com"}, NetworkLogDeniedDomains: true}
+	tests := []struct {
+		name    string
+		check   func(s *Syscall) bool
+		network runtime.NetworkConfig
+		want    bool
+	}{
+		{"without allowlist", sendto(dnsQuery("evil.test")), client, true},
+		{"allowed domain", sendto(dnsQuery("www.example.com")), domains, true},
+		{"denied domain", sendto(dnsQuery("evil.test")), domains, false},
+		{"denied domain logged", sendto(dnsQuery("evil.test")), logged, true},
+		{"not a query", sendto([]byte("exfiltrated data")), domains, false},
+		{"tcp allowed domain", writev(tcp, dnsQuery("example.com")), domains, true},
+		{"tcp denied domain", writev(tcp, dnsQuery("evil.test")), domains, false},
+		{"tcp pipelined allowed domains", write(append(tcpQuery(dnsQuery("example.com")), tcpQuery(dnsQuery("www.example.com"))...)), domains, true},
+		{"tcp pipelined denied domain", write(pipelined), domains, false},
+		{"tcp partial query", write(partial), domains, false},
+		{"tcp length prefix only", write([]byte{0, 29}), domains, false},
+		{"other port", writev(web, []byte("exfiltrated data")), domains, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{NetworkConfig: tt.network},
+			}
+
+			if got := tt.check(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Queried names are audited with and without allowed domains.
+	for _, network := range []runtime.NetworkConfig{client, domains, logged} {
+		var b bytes.Buffer
+		s := Syscall{
+			TraceePID: os.Getpid(),
+			Fds:       fds,
+			Config:    &runtime.Config{NetworkConfig: network},
+			Audit:     audit.New(&b),
+		}
+		allowed := sendto(dnsQuery("evil.test"))(&s)
+
+		entries := auditEntries(t, &b)
+		want := audit.Allowed
+		if len(network.NetworkAllowedDomains) > 0 {
+			want = audit.Denied
+			if network.NetworkLogDeniedDomains {
+				want = audit.Logged
+			}
+		}
+		if len(entries) == 0 || entries[0].Event != "dns" || entries[0].Host != "evil.test" || entries[0].Remote != "192.0.2.53:53" || entries[0].Decision != want {
+			t.Fatalf("expected the query to be audited as %s, got %+v", want, entries)
+		}
+		if allowed != (want != audit.Denied) {
+			t.Fatalf("expected the query to be allowed %v, got %v", want != audit.Denied, allowed)
+		}
+	}
+}
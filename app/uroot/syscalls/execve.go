@@ -0,0 +1,64 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsExecveAllowed checks execve(pathname, argv, envp).
+// pathname is arg 0.
+func IsExecveAllowed(s Syscall, isEnter bool) bool {
+	if isFilelessExec(s, 0, -1, 0) {
+		return false
+	}
+	return PathIsAllowed(s, 0, -1)
+}
+
+// IsExecveAtAllowed checks execveat(dirfd, pathname, argv, envp, flags).
+// pathname is arg 1, dirfd is arg 0. With the memory hardening of the
+// configuration, a memfd cannot be executed through AT_EMPTY_PATH, which is
+// how fexecve runs programs that never touched the filesystem.
+func IsExecveAtAllowed(s Syscall, isEnter bool) bool {
+	flags := int(s.Args[4].Int())
+	if isFilelessExec(s, 1, 0, flags) {
+		return false
+	}
+	return PathAtIsAllowed(s, 1, 0, flags)
+}
+
+// isFilelessExec reports whether the memory hardening of the configuration
+// denies executing the program of execve or execveat because it is
+// anonymous shared memory, e.g. a memfd. It is reached through an empty path
+// with AT_EMPTY_PATH, or through its /proc/<pid>/fd link.
+func isFilelessExec(s Syscall, pathArgIndex int, dirfdArgIndex int, flags int) bool {
+	if !s.config().MemoryDenyWriteExecute {
+		return false
+	}
+	path, err := readPath(s, s.Args[pathArgIndex].Pointer(), 4096)
+	if err != nil {
+		// The kernel fails with EFAULT
+		return false
+	}
+
+	if path == "" && flags&unix.AT_EMPTY_PATH != 0 {
+		path = traceeFdPath(s, s.Args[dirfdArgIndex].Int())
+	} else {
+		dirfd := unix.AT_FDCWD
+		if dirfdArgIndex >= 0 {
+			dirfd = int(s.Args[dirfdArgIndex].Int())
+		}
+		resolved, err := resolveTraceePath(s.TraceePID, dirfd, path, flags&unix.AT_SYMLINK_NOFOLLOW == 0)
+		if err != nil {
+			return false
+		}
+		path = resolved.Path
+	}
+	if isAnonymousSharedMemory(path) {
+		fmt.Printf("executing %s is not allowed\n", path)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsExecveAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsExecveAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: filepath.Join(allowed, "file")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+		{name: "symlink to outside", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: false},
+	})
+}
+
+func TestIsExecveAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsExecveAtAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "empty path of fd", paths: map[int]string{1: ""}, ints: map[int]uintptr{0: 3, 4: unix.AT_EMPTY_PATH}, want: true},
+	})
+}
@@ -14,11 +14,14 @@ import (
 
 func TestFaccessAtAllowedAbsolute(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = true
 
 	path := filepath.Join(d, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x9000)
 	// dirfd at arg 0 (not used for absolute path)
 	s.Args[0] = SyscallArgument{Value: uintptr(0)}
@@ -33,12 +36,15 @@ func TestFaccessAtAllowedAbsolute(t *testing.T) {
 
 func TestFaccessAtNotAllowedAbsolute(t *testing.T) {
 	allowed := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{allowed}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{allowed}
+	conf.FileSystemAllowRead = true
 
 	another := t.TempDir()
 	path := filepath.Join(another, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0xA000)
 	s.Args[0] = SyscallArgument{Value: uintptr(0)}
 	s.Args[1] = SyscallArgument{Value: base}
@@ -51,12 +57,15 @@ func TestFaccessAtNotAllowedAbsolute(t *testing.T) {
 
 func TestFaccessAtAllowedWithATFDCWD(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = true
 
 	relPath := "subdir/file.txt"
 	// Use current working directory as tracee cwd via AT_FDCWD
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0xB000)
 	// dirfd at arg 0 is AT_FDCWD
 	v := unix.AT_FDCWD
@@ -87,11 +96,14 @@ func TestFaccessAtAllowedWithATFDCWD(t *testing.T) {
 
 func TestFaccessAtDeniedWhenReadNotAllowed(t *testing.T) {
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = false
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = false
 
 	path := filepath.Join(d, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0xC000)
 	s.Args[0] = SyscallArgument{Value: uintptr(0)}
 	s.Args[1] = SyscallArgument{Value: base}
@@ -106,8 +118,9 @@ func TestFaccessAtAllowedRelativeWithDirfdAndFdResolution(t *testing.T) {
 	// This is essentially the same as the earlier test but verifies that
 	// resolving /proc/<pid>/fd/<fd> works as expected.
 	d := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{d}
-	runtime.Get().FileSystemAllowRead = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{d}
+	conf.FileSystemAllowRead = true
 
 	relPath := "subdir/file.txt"
 	f, err := os.Open(d)
@@ -118,6 +131,7 @@ func TestFaccessAtAllowedRelativeWithDirfdAndFdResolution(t *testing.T) {
 	fd := int(f.Fd())
 
 	var s Syscall
+	s.Config = conf
 	base := uintptr(0xD000)
 	// dirfd at arg 0
 	s.Args[0] = SyscallArgument{Value: uintptr(fd)}
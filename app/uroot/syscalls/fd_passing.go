@@ -0,0 +1,332 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxControlLen bounds the control buffer read from the tracee. The kernel
+// limits the ancillary data of a message to the optmem_max sysctl, which is
+// well below this.
+const maxControlLen = 1 << 20
+
+// maxMmsgs bounds the messages of sendmmsg and recvmmsg that are inspected,
+// the kernel handles at most UIO_MAXIOV of them.
+const maxMmsgs = 1024
+
+// Msghdr is struct msghdr of the 64-bit ABIs as read from tracee memory.
+type Msghdr struct {
+	Name       uint64
+	Namelen    uint32
+	_          uint32
+	Iov        uint64
+	Iovlen     uint64
+	Control    uint64
+	Controllen uint64
+	Flags      int32
+	_          int32
+}
+
+// Mmsghdr is struct mmsghdr of sendmmsg and recvmmsg.
+type Mmsghdr struct {
+	Hdr Msghdr
+	Len uint32
+	_   uint32
+}
+
+// ReadMsghdrs reads the messages of a sendmsg or recvmsg (vlen 1) or of a
+// sendmmsg or recvmmsg call at addr. The kernel handles the messages of a
+// msgvec one by one and returns the count of those it handled before one
+// faulted, so the messages are read one by one as well and the ones that can
+// be read are returned. It fails if not even the first message can be read.
+func ReadMsghdrs(read func(addr Addr, v interface{}) (int, error), addr Addr, vlen int, mmsg bool) ([]Msghdr, error) {
+	if !mmsg {
+		var hdr Msghdr
+		if _, err := read(addr, &hdr); err != nil {
+			return nil, err
+		}
+		return []Msghdr{hdr}, nil
+	}
+
+	vlen = min(vlen, maxMmsgs)
+	msgs := make([]Msghdr, 0, vlen)
+	size := Addr(binary.Size(Mmsghdr{}))
+	for i := 0; i < vlen; i++ {
+		var h Mmsghdr
+		if _, err := read(addr+Addr(i)*size, &h); err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			break
+		}
+		msgs = append(msgs, h.Hdr)
+	}
+	return msgs, nil
+}
+
+// ReadRights returns the fds of the SCM_RIGHTS control messages of hdr.
+func ReadRights(read func(addr Addr, v interface{}) (int, error), hdr Msghdr) ([]int32, error) {
+	if hdr.Controllen == 0 {
+		return nil, nil
+	}
+	if hdr.Control == 0 {
+		// Control data, sent or received, has a buffer.
+		return nil, errors.New("control length without a control buffer")
+	}
+	control := make([]byte, min(hdr.Controllen, maxControlLen))
+	if _, err := read(Addr(hdr.Control), control); err != nil {
+		return nil, err
+	}
+	msgs, err := unix.ParseSocketControlMessage(control)
+	if err != nil {
+		return nil, err
+	}
+
+	var fds []int32
+	for _, m := range msgs {
+		if m.Header.Level != unix.SOL_SOCKET || m.Header.Type != unix.SCM_RIGHTS {
+			continue
+		}
+		rights, err := unix.ParseUnixRights(&m)
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range rights {
+			fds = append(fds, int32(fd))
+		}
+	}
+	return fds, nil
+}
+
+// ReceivedRights returns the fds passed with SCM_RIGHTS to the tracee by a
+// recvmsg or recvmmsg that returned ret. The kernel set the control length of
+// the received messages to the control data it wrote. The fds of the
+// messages that can be read are returned along with the first error.
+func ReceivedRights(s Syscall, mmsg bool, ret int) ([]int32, error) {
+	vlen := 1
+	if mmsg {
+		// recvmmsg returns the number of messages received
+		vlen = ret
+	}
+	msgs, err := ReadMsghdrs(s.Reader, s.Args[1].Pointer(), vlen, mmsg)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int32
+	var firstErr error
+	for _, hdr := range msgs {
+		rights, err := ReadRights(s.Reader, hdr)
+		if err != nil {
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, firstErr
+}
+
+// unixSocketPaths returns the paths a Unix socket of the tracee is bound and
+// connected to. Abstract addresses start with "@". A copy of the socket is
+// taken with pidfd_getfd, so the addresses are the ones the kernel uses.
+func unixSocketPaths(s Syscall, fd int32) ([]string, error) {
+	pidfd, err := unix.PidfdOpen(s.TraceePID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(pidfd)
+	sock, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(sock)
+
+	var paths []string
+	for _, get := range []func(int) (unix.Sockaddr, error){unix.Getsockname, unix.Getpeername} {
+		sa, err := get(sock)
+		if err != nil {
+			// ENOTCONN for sockets without a peer
+			continue
+		}
+		if addr, ok := sa.(*unix.SockaddrUnix); ok && addr.Name != "" {
+			paths = append(paths, traceeSocketPath(s, addr.Name))
+		}
+	}
+	return paths, nil
+}
+
+// traceeSocketPath makes a relative socket path of the tracee absolute. The
+// kernel reports Unix socket paths as they were given to bind.
+func traceeSocketPath(s Syscall, name string) string {
+	if strings.HasPrefix(name, "@") || strings.HasPrefix(name, "/") {
+		return name
+	}
+	cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", s.TraceePID))
+	if err != nil {
+		return name
+	}
+	return filepath.Join(cwd, name)
+}
+
+// readUnixSockaddr reads the Unix socket path of the sockaddr at addr, or
+// returns "" if it is not an AF_UNIX address.
+func readUnixSockaddr(s Syscall, addr Addr, length uint32) string {
+	if addr == 0 || length <= 2 || s.Reader == nil {
+		return ""
+	}
+	raw := make([]byte, min(int(length), unix.SizeofSockaddrUnix))
+	if _, err := s.Reader(addr, raw); err != nil {
+		return ""
+	}
+	if binary.NativeEndian.Uint16(raw) != unix.AF_UNIX {
+		return ""
+	}
+	name := raw[2:]
+	if name[0] == 0 {
+		// abstract socket, the name is not NUL terminated
+		return "@" + string(name[1:])
+	}
+	if i := slices.Index(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return traceeSocketPath(s, string(name))
+}
+
+// isFdPassingAllowed decides whether fds may be passed over the Unix socket
+// fd of the tracee. dest is the path of the receiver for unconnected
+// sockets, if known. Passed fds bypass the path checks of the receiver, so
+// passing is only allowed if enabled and, if a list of socket paths is
+// configured, if the socket is bound or connected to one of them.
+func isFdPassingAllowed(s Syscall, fd int32, dest string) bool {
+	c := s.config()
+	if !c.LocalSocketsAllowFdPassing {
+		fmt.Printf("passing fds over socket %d is not allowed\n", fd)
+		return false
+	}
+	if len(c.LocalSocketsFdPassingPaths) == 0 {
+		return true
+	}
+
+	paths, err := unixSocketPaths(s, fd)
+	if err != nil {
+		fmt.Printf("unable to look up socket %d of pid %d: %s\n", fd, s.TraceePID, err.Error())
+		return false
+	}
+	if dest != "" {
+		paths = append(paths, dest)
+	}
+	for _, p := range paths {
+		if slices.Contains(c.LocalSocketsFdPassingPaths, p) {
+			return true
+		}
+	}
+	fmt.Printf("passing fds over socket %d at %v is not allowed\n", fd, paths)
+	return false
+}
+
+// isUnixSocket returns true if fd of the tracee might be a Unix socket.
+func isUnixSocket(s Syscall, fd int32) bool {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok && info.Family != 0 {
+			return info.Family == unix.AF_UNIX
+		}
+	}
+	pidfd, err := unix.PidfdOpen(s.TraceePID, 0)
+	if err != nil {
+		fmt.Printf("unable to open pid %d to look up fd %d, assuming a Unix socket: %s\n", s.TraceePID, fd, err.Error())
+		return true
+	}
+	defer unix.Close(pidfd)
+	sock, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+	if err != nil {
+		fmt.Printf("unable to get fd %d of pid %d, assuming a Unix socket: %s\n", fd, s.TraceePID, err.Error())
+		return true
+	}
+	defer unix.Close(sock)
+	domain, err := unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_DOMAIN)
+	return err != nil || domain == unix.AF_UNIX
+}
+
+// isSendRightsAllowed checks the messages of sendmsg(sockfd, msg, flags)
+// or sendmmsg(sockfd, msgvec, vlen, flags) for SCM_RIGHTS.
+func isSendRightsAllowed(s Syscall, mmsg bool) bool {
+	if s.Reader == nil {
+		return false
+	}
+	fd := s.Args[0].Int()
+	msgs, err := ReadMsghdrs(s.Reader, s.Args[1].Pointer(), int(s.Args[2].Uint()), mmsg)
+	if err != nil {
+		fmt.Printf("unable to read messages of pid %d: %s\n", s.TraceePID, err.Error())
+		return false
+	}
+	for _, hdr := range msgs {
+		rights, err := ReadRights(s.Reader, hdr)
+		if err != nil {
+			fmt.Printf("unable to read control messages of pid %d: %s\n", s.TraceePID, err.Error())
+			return false
+		}
+		if len(rights) == 0 {
+			continue
+		}
+		dest := readUnixSockaddr(s, Addr(hdr.Name), hdr.Namelen)
+		if !isFdPassingAllowed(s, fd, dest) {
+			return false
+		}
+	}
+	return true
+}
+
+// isReceiveRightsAllowed checks the messages of recvmsg(sockfd, msg, flags)
+// or recvmmsg(sockfd, msgvec, vlen, flags, timeout). Whether fds arrive is
+// only known after the call, so a Unix socket may only be read with a
+// control buffer that can hold an fd if fds may be passed over it. With a
+// smaller buffer, the kernel closes passed fds instead of installing them.
+//
+// Fds can arrive from processes outside the sandbox, so receiving is
+// restricted even though sending is checked. Control buffers for
+// SCM_CREDENTIALS alone are large enough to hold an fd, so they are denied
+// as well.
+func isReceiveRightsAllowed(s Syscall, mmsg bool) bool {
+	if s.Reader == nil {
+		return false
+	}
+	fd := s.Args[0].Int()
+	msgs, err := ReadMsghdrs(s.Reader, s.Args[1].Pointer(), int(s.Args[2].Uint()), mmsg)
+	if err != nil {
+		fmt.Printf("unable to read messages of pid %d: %s\n", s.TraceePID, err.Error())
+		return false
+	}
+	canReceive := slices.ContainsFunc(msgs, func(hdr Msghdr) bool {
+		return hdr.Control != 0 && hdr.Controllen >= uint64(unix.CmsgLen(4))
+	})
+	return !canReceive || !isUnixSocket(s, fd) || isFdPassingAllowed(s, fd, "")
+}
+
+// IsReceivedRightsAllowed decides at exit whether the fds a recvmsg or
+// recvmmsg received may be kept. The messages are checked at entry, but
+// another thread of the tracee can add a control buffer to them before the
+// kernel reads them.
+func IsReceivedRightsAllowed(s Syscall) bool {
+	fd := s.Args[0].Int()
+	return !isUnixSocket(s, fd) || isFdPassingAllowed(s, fd, "")
+}
+
+// IsRecvmsgAllowed checks recvmsg(sockfd, msg, flags) like read, and
+// whether fds can be received with it.
+func IsRecvmsgAllowed(s Syscall, isEnter bool) bool {
+	return IsReadAllowed(s, isEnter) && isReceiveRightsAllowed(s, false)
+}
+
+// IsRecvmmsgAllowed checks recvmmsg(sockfd, msgvec, vlen, flags, timeout).
+func IsRecvmmsgAllowed(s Syscall, isEnter bool) bool {
+	return IsReadAllowed(s, isEnter) && isReceiveRightsAllowed(s, true)
+}
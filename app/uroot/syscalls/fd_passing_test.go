@@ -0,0 +1,232 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"golang.org/x/sys/unix"
+)
+
+// fakeMemory maps fake tracee addresses to their contents.
+type fakeMemory map[uintptr][]byte
+
+func (m fakeMemory) read(addr Addr, v interface{}) (int, error) {
+	for base, b := range m {
+		if uintptr(addr) >= base && uintptr(addr) < base+uintptr(len(b)) {
+			r := bytes.NewReader(b[uintptr(addr)-base:])
+			err := binary.Read(r, binary.NativeEndian, v)
+			return binary.Size(v), err
+		}
+	}
+	return 0, fmt.Errorf("out of range read: %#x", addr)
+}
+
+func encode(t *testing.T, v interface{}) []byte {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.NativeEndian, v); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// unixConn returns the fd of a client connected to a Unix socket at path.
+func unixConn(t *testing.T, path string) int {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	f, err := c.(*net.UnixConn).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return int(f.Fd())
+}
+
+func TestFdPassing(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedSock := filepath.Join(td, "allowed.sock")
+	otherSock := filepath.Join(td, "other.sock")
+	allowed := unixConn(t, allowedSock)
+	other := unixConn(t, otherSock)
+	udp, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(udp)
+
+	const (
+		hdrAddr     = 0x1000
+		controlAddr = 0x2000
+	)
+	rights := unix.UnixRights(0)
+	withRights := fakeMemory{
+		hdrAddr:     encode(t, Msghdr{Control: controlAddr, Controllen: uint64(len(rights))}),
+		controlAddr: rights,
+	}
+	withoutRights := fakeMemory{
+		hdrAddr: encode(t, Msghdr{}),
+	}
+	receiveBuffer := fakeMemory{
+		hdrAddr: encode(t, Msghdr{Control: controlAddr, Controllen: 64}),
+	}
+	smallReceiveBuffer := fakeMemory{
+		hdrAddr: encode(t, Msghdr{Control: controlAddr, Controllen: 16}),
+	}
+	mmsgWithRights := fakeMemory{
+		hdrAddr: encode(t, []Mmsghdr{
+			{},
+			{Hdr: Msghdr{Control: controlAddr, Controllen: uint64(len(rights))}},
+		}),
+		controlAddr: rights,
+	}
+	// The msgvecs end before an unmapped page.
+	mmsgReceiveBuffer := fakeMemory{
+		hdrAddr: encode(t, []Mmsghdr{{Hdr: Msghdr{Control: controlAddr, Controllen: 64}}}),
+	}
+	unmapped := fakeMemory{}
+
+	disabled := runtime.NetworkConfig{LocalSocketsAllow: true, NetworkAllowClient: true}
+	enabled := runtime.NetworkConfig{LocalSocketsAllow: true, LocalSocketsAllowFdPassing: true}
+	restricted := runtime.NetworkConfig{LocalSocketsAllow: true, LocalSocketsAllowFdPassing: true, LocalSocketsFdPassingPaths: []string{allowedSock}}
+
+	tests := []struct {
+		name    string
+		check   func(s Syscall, isEnter bool) bool
+		fd      int
+		mem     fakeMemory
+		vlen    uintptr
+		network runtime.NetworkConfig
+		want    bool
+	}{
+		{"sendmsg without rights", IsSendmsgAllowed, other, withoutRights, 0, disabled, true},
+		{"sendmsg rights disabled", IsSendmsgAllowed, other, withRights, 0, disabled, false},
+		{"sendmsg rights enabled", IsSendmsgAllowed, other, withRights, 0, enabled, true},
+		{"sendmsg rights on allowed socket", IsSendmsgAllowed, allowed, withRights, 0, restricted, true},
+		{"sendmsg rights on other socket", IsSendmsgAllowed, other, withRights, 0, restricted, false},
+		{"sendmmsg rights in second message", IsSendmmsgAllowed, other, mmsgWithRights, 2, disabled, false},
+		{"sendmmsg only first message", IsSendmmsgAllowed, other, mmsgWithRights, 1, disabled, true},
+		{"sendmmsg msgvec partly unmapped", IsSendmmsgAllowed, other, mmsgWithRights, maxMmsgs, disabled, false},
+		{"sendmsg unmapped message", IsSendmsgAllowed, other, unmapped, 0, enabled, false},
+		{"recvmsg without control buffer", IsRecvmsgAllowed, other, withoutRights, 0, disabled, true},
+		{"recvmsg control buffer too small for fds", IsRecvmsgAllowed, other, smallReceiveBuffer, 0, disabled, true},
+		{"recvmsg control buffer disabled", IsRecvmsgAllowed, other, receiveBuffer, 0, disabled, false},
+		{"recvmsg control buffer enabled", IsRecvmsgAllowed, other, receiveBuffer, 0, enabled, true},
+		{"recvmsg control buffer on allowed socket", IsRecvmsgAllowed, allowed, receiveBuffer, 0, restricted, true},
+		{"recvmsg control buffer on other socket", IsRecvmsgAllowed, other, receiveBuffer, 0, restricted, false},
+		{"recvmsg control buffer on inet socket", IsRecvmsgAllowed, udp, receiveBuffer, 0, disabled, true},
+		{"recvmmsg msgvec partly unmapped", IsRecvmmsgAllowed, other, mmsgReceiveBuffer, maxMmsgs, disabled, false},
+		{"recvmmsg msgvec partly unmapped enabled", IsRecvmmsgAllowed, other, mmsgReceiveBuffer, maxMmsgs, enabled, true},
+		{"recvmsg unmapped message", IsRecvmsgAllowed, other, unmapped, 0, enabled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Reader:    tt.mem.read,
+				Config:    &runtime.Config{NetworkConfig: tt.network},
+			}
+			s.Args[0] = SyscallArgument{Value: uintptr(tt.fd)}
+			s.Args[1] = SyscallArgument{Value: hdrAddr}
+			s.Args[2] = SyscallArgument{Value: tt.vlen}
+
+			if got := tt.check(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadMsghdrs(t *testing.T) {
+	const hdrAddr = 0x1000
+	mem := fakeMemory{hdrAddr: encode(t, []Mmsghdr{{Hdr: Msghdr{Namelen: 1}}, {Hdr: Msghdr{Namelen: 2}}})}
+
+	// Only the messages before the unmapped page are returned.
+	msgs, err := ReadMsghdrs(mem.read, hdrAddr, maxMmsgs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Namelen != 1 || msgs[1].Namelen != 2 {
+		t.Fatalf("expected the 2 readable messages, got %+v", msgs)
+	}
+	if _, err := ReadMsghdrs(mem.read, 0x8000, maxMmsgs, true); err == nil {
+		t.Fatal("expected an unmapped msgvec not to be read")
+	}
+}
+
+func TestReceivedRights(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := unixConn(t, filepath.Join(td, "sock"))
+
+	const (
+		hdrAddr     = 0x1000
+		controlAddr = 0x2000
+	)
+	rights := unix.UnixRights(5, 6)
+	mem := fakeMemory{
+		hdrAddr: encode(t, []Mmsghdr{
+			{},
+			{Hdr: Msghdr{Control: controlAddr, Controllen: uint64(len(rights))}},
+		}),
+		controlAddr: rights,
+	}
+	s := Syscall{
+		TraceePID: os.Getpid(),
+		Reader:    mem.read,
+		Config:    &runtime.Config{NetworkConfig: runtime.NetworkConfig{LocalSocketsAllow: true}},
+	}
+	s.Args[0] = SyscallArgument{Value: uintptr(sock)}
+	s.Args[1] = SyscallArgument{Value: hdrAddr}
+
+	// Only the messages recvmmsg returned are read.
+	if fds, err := ReceivedRights(s, true, 1); err != nil || len(fds) != 0 {
+		t.Fatalf("expected no fds in the first message, got %v %v", fds, err)
+	}
+	fds, err := ReceivedRights(s, true, 2)
+	if err != nil || len(fds) != 2 || fds[0] != 5 || fds[1] != 6 {
+		t.Fatalf("expected fds [5 6], got %v %v", fds, err)
+	}
+
+	if IsReceivedRightsAllowed(s) {
+		t.Fatal("expected received fds not to be allowed without fd passing")
+	}
+	s.Config.LocalSocketsAllowFdPassing = true
+	if !IsReceivedRightsAllowed(s) {
+		t.Fatal("expected received fds to be allowed with fd passing")
+	}
+}
+
+func TestReadRights(t *testing.T) {
+	const controlAddr = 0x2000
+	control := append(unix.UnixCredentials(&unix.Ucred{Pid: 1}), unix.UnixRights(3, 4)...)
+	mem := fakeMemory{controlAddr: control}
+
+	fds, err := ReadRights(mem.read, Msghdr{Control: controlAddr, Controllen: uint64(len(control))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fds) != 2 || fds[0] != 3 || fds[1] != 4 {
+		t.Fatalf("expected fds [3 4], got %v", fds)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"net/netip"
+
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
+// isTransferAllowed decides whether data may be moved from fd in to fd out
+// by the kernel, without passing through the tracee's memory. The tracee
+// needs the same permissions as for reading in and writing out itself. Data
+// that would be inspected on a socket cannot be, so it is refused.
+func isTransferAllowed(s Syscall, in int32, out int32) bool {
+	return isReadFdAllowed(s, in) && isWriteFdAllowed(s, out) && isSentDataAllowed(s, out, netip.AddrPort{}, nil)
+}
+
+// IsSendfileAllowed checks sendfile(out_fd, in_fd, offset, count).
+func IsSendfileAllowed(s Syscall, isEnter bool) bool {
+	return isTransferAllowed(s, s.Args[1].Int(), s.Args[0].Int())
+}
+
+// IsSpliceAllowed checks splice(fd_in, off_in, fd_out, off_out, len, flags).
+func IsSpliceAllowed(s Syscall, isEnter bool) bool {
+	return isTransferAllowed(s, s.Args[0].Int(), s.Args[2].Int())
+}
+
+// IsTeeAllowed checks tee(fd_in, fd_out, len, flags).
+func IsTeeAllowed(s Syscall, isEnter bool) bool {
+	return isTransferAllowed(s, s.Args[0].Int(), s.Args[1].Int())
+}
+
+// IsCopyFileRangeAllowed checks copy_file_range(fd_in, off_in, fd_out, off_out, len, flags).
+func IsCopyFileRangeAllowed(s Syscall, isEnter bool) bool {
+	return isTransferAllowed(s, s.Args[0].Int(), s.Args[2].Int())
+}
+
+// IsVmspliceAllowed checks vmsplice(fd, iov, nr_segs, flags). Data moves
+// between the tracee's memory and a pipe, so fd must be a pipe.
+func IsVmspliceAllowed(s Syscall, isEnter bool) bool {
+	return s.FdType(s.Args[0].Int()) == args.FDPipe
+}
@@ -0,0 +1,65 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
+func TestFdTransferAllowed(t *testing.T) {
+	const (
+		file   = 1000
+		pipe   = 1001
+		socket = 1002
+	)
+	fds := args.NewFdTable()
+	fds.Set(file, args.FdInfo{Type: args.FDFile})
+	fds.Set(pipe, args.FdInfo{Type: args.FDPipe})
+	fds.Set(socket, args.FdInfo{Type: args.FDSocket})
+
+	readOnly := runtime.FsConfig{FileSystemAllowRead: true}
+	readWrite := runtime.FsConfig{FileSystemAllowRead: true, FileSystemAllowWrite: true}
+
+	tests := []struct {
+		name  string
+		check func(s Syscall, isEnter bool) bool
+		args  []uintptr
+		fs    runtime.FsConfig
+		want  bool
+	}{
+		{"splice file to pipe read-only", IsSpliceAllowed, []uintptr{file, 0, pipe}, readOnly, true},
+		{"splice pipe to file read-only", IsSpliceAllowed, []uintptr{pipe, 0, file}, readOnly, false},
+		{"splice pipe to file read-write", IsSpliceAllowed, []uintptr{pipe, 0, file}, readWrite, true},
+		{"splice file to socket without network", IsSpliceAllowed, []uintptr{file, 0, socket}, readWrite, false},
+		{"sendfile file to pipe read-only", IsSendfileAllowed, []uintptr{pipe, file}, readOnly, true},
+		{"sendfile pipe to file read-only", IsSendfileAllowed, []uintptr{file, pipe}, readOnly, false},
+		{"copy_file_range read-only", IsCopyFileRangeAllowed, []uintptr{file, 0, file}, readOnly, false},
+		{"copy_file_range read-write", IsCopyFileRangeAllowed, []uintptr{file, 0, file}, readWrite, true},
+		{"tee between pipes", IsTeeAllowed, []uintptr{pipe, pipe}, runtime.FsConfig{}, true},
+		{"vmsplice to pipe", IsVmspliceAllowed, []uintptr{pipe}, runtime.FsConfig{}, true},
+		{"vmsplice to file", IsVmspliceAllowed, []uintptr{file}, readWrite, false},
+		{"pwrite64 read-only", IsWriteAllowed, []uintptr{file}, readOnly, false},
+		{"pread64 read-only", IsReadAllowed, []uintptr{file}, readOnly, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{FsConfig: tt.fs},
+			}
+			for i, a := range tt.args {
+				s.Args[i] = SyscallArgument{Value: a}
+			}
+
+			if got := tt.check(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
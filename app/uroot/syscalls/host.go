@@ -0,0 +1,232 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"github.com/cuandari/lib/app/utils"
+	"golang.org/x/sys/unix"
+)
+
+// maxFirstWriteLen bounds the data read from the tracee to find the host of
+// a connection: a TLS record holds at most 16 KiB.
+const maxFirstWriteLen = 5 + 16384
+
+// isFirstWriteHostAllowed inspects the first data sent on TCP connection fd
+// of the tracee. It must be a TLS ClientHello with a server name, or a plain
+// HTTP request, for one of the allowed hosts, as IP allowlists cannot tell
+// apart services behind the same load balancer. Later writes on the
+// connection are not inspected. Sockets without a peer in the shadow fd
+// table, e.g. duplicated before connect or received from another process,
+// are looked up. Connections the tracee accepted and the standard streams
+// are not inspected.
+func isFirstWriteHostAllowed(s Syscall, fd int32, data sentData) bool {
+	c := s.config()
+	if len(c.NetworkAllowedHosts) == 0 || s.Fds == nil {
+		return true
+	}
+	info, ok := s.Fds.Get(fd)
+	if ok && (info.Type != args.FDSocket || info.Sent || info.Stdio || (info.SockType != 0 && info.SockType != unix.SOCK_STREAM)) {
+		return true
+	}
+	if !ok || !info.Peer.IsValid() {
+		// The socket may have been connected through another fd, e.g. one
+		// it was duplicated from before connect, or it was received from
+		// another process. Its peer is looked up, and recorded so that
+		// only its first data is inspected.
+		peer, connected := streamSocketPeer(s, fd)
+		if !connected {
+			return true
+		}
+		if !ok {
+			info = args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_STREAM}
+		}
+		info.Peer = peer
+		s.Fds.Set(fd, info)
+	}
+	if info.Peer.Port() == dnsPort {
+		// DNS over TCP is inspected by isDNSQueryAllowed
+		return true
+	}
+
+	msg := data.read(maxFirstWriteLen)
+	protocol := "TLS"
+	host, ok := parseClientHelloServerName(msg)
+	if !ok {
+		protocol = "HTTP"
+		host, ok = parseHTTPHost(msg)
+	}
+	if !ok || host == "" {
+		fmt.Printf("connection of pid %d to %s does not start with a TLS ClientHello or an HTTP request for a host\n", s.TraceePID, info.Peer)
+		auditHost(s, "host", "", info.Peer, "", audit.Denied)
+		return false
+	}
+
+	allowed := utils.MatchDomain(c.NetworkAllowedHosts, host)
+	fmt.Printf("%s connection of pid %d to %s for host %s allowed %v\n", protocol, s.TraceePID, info.Peer, host, allowed)
+	auditHost(s, "host", strings.ToLower(protocol), info.Peer, host, decision(allowed))
+	return allowed
+}
+
+// streamSocketPeer returns the peer of fd of the tracee if it is a connected
+// AF_INET or AF_INET6 stream socket. It is looked up on a duplicate from
+// pidfd_getfd.
+func streamSocketPeer(s Syscall, fd int32) (netip.AddrPort, bool) {
+	typ := -1
+	sa, err := traceeSockaddr(s, fd, func(sock int) (unix.Sockaddr, error) {
+		var err error
+		if typ, err = unix.GetsockoptInt(sock, unix.SOL_SOCKET, unix.SO_TYPE); err != nil {
+			return nil, err
+		}
+		return unix.Getpeername(sock)
+	})
+	if err != nil || typ != unix.SOCK_STREAM {
+		return netip.AddrPort{}, false
+	}
+	return inetAddrPort(sa)
+}
+
+// isFastOpenAllowed refuses MSG_FASTOPEN while hosts are allowlisted. The
+// send connects the socket, so its data is not known to be the first data on
+// a connection.
+func isFastOpenAllowed(s Syscall, flags uint32) bool {
+	return flags&unix.MSG_FASTOPEN == 0 || len(s.config().NetworkAllowedHosts) == 0
+}
+
+// tlsVector returns the vector at b[off:] with a length prefix of n bytes and
+// the offset following it.
+func tlsVector(b []byte, off int, n int) ([]byte, int, bool) {
+	if off+n > len(b) {
+		return nil, 0, false
+	}
+	length := 0
+	for _, c := range b[off : off+n] {
+		length = length<<8 | int(c)
+	}
+	off += n
+	if off+length > len(b) {
+		return nil, 0, false
+	}
+	return b[off : off+length], off + length, true
+}
+
+// parseClientHelloServerName returns the server name of the TLS ClientHello
+// in msg. A ClientHello without the server_name extension has an empty name.
+// A ClientHello fragmented over several records is not parsed.
+func parseClientHelloServerName(msg []byte) (string, bool) {
+	// Record header: content type handshake, version, length
+	if len(msg) < 5 || msg[0] != 0x16 {
+		return "", false
+	}
+	record, _, ok := tlsVector(msg, 3, 2)
+	if !ok {
+		return "", false
+	}
+	// Handshake header: type client_hello, length
+	if len(record) < 1 || record[0] != 0x01 {
+		return "", false
+	}
+	hello, _, ok := tlsVector(record, 1, 3)
+	if !ok {
+		return "", false
+	}
+
+	// client_version and random
+	off := 2 + 32
+	// session_id, cipher_suites and compression_methods
+	for _, n := range []int{1, 2, 1} {
+		if _, off, ok = tlsVector(hello, off, n); !ok {
+			return "", false
+		}
+	}
+	if off == len(hello) {
+		return "", true
+	}
+	extensions, _, ok := tlsVector(hello, off, 2)
+	if !ok {
+		return "", false
+	}
+
+	for off = 0; off+4 <= len(extensions); {
+		typ := binary.BigEndian.Uint16(extensions[off:])
+		var ext []byte
+		if ext, off, ok = tlsVector(extensions, off+2, 2); !ok {
+			return "", false
+		}
+		if typ != 0 {
+			continue
+		}
+		// server_name: a list of a name type and a name
+		list, _, ok := tlsVector(ext, 0, 2)
+		if !ok {
+			return "", false
+		}
+		for i := 0; i < len(list); {
+			nameType := list[i]
+			var name []byte
+			if name, i, ok = tlsVector(list, i+1, 2); !ok {
+				return "", false
+			}
+			if nameType == 0 {
+				return strings.TrimSuffix(strings.ToLower(string(name)), "."), true
+			}
+		}
+		return "", true
+	}
+	return "", true
+}
+
+// parseHTTPHost returns the host an HTTP/1 request in msg is for: the
+// authority of its request line if it has one, e.g. for CONNECT or a proxy
+// request, otherwise its Host header. Conflicting Host headers, and headers
+// that are not complete in msg, are refused.
+func parseHTTPHost(msg []byte) (string, bool) {
+	head, _, complete := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !complete {
+		return "", false
+	}
+	lines := strings.Split(string(head), "\r\n")
+	request := strings.Split(lines[0], " ")
+	if len(request) != 3 || !strings.HasPrefix(request[2], "HTTP/1.") || request[0] == "" || strings.ToUpper(request[0]) != request[0] {
+		return "", false
+	}
+
+	var authority string
+	switch target := request[1]; {
+	case request[0] == "CONNECT":
+		authority = target
+	case strings.Contains(target, "://"):
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", false
+		}
+		authority = u.Host
+	default:
+		for _, line := range lines[1:] {
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "host") {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if authority != "" && authority != value {
+				return "", false
+			}
+			authority = value
+		}
+	}
+
+	host := authority
+	if h, _, err := net.SplitHostPort(authority); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), "."), true
+}
@@ -0,0 +1,207 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+// clientHello returns the first TLS record a client sends to serverName.
+func clientHello(t *testing.T, serverName string) []byte {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: serverName}).Handshake()
+		client.Close()
+	}()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(server, header); err != nil {
+		t.Fatal(err)
+	}
+	record := make([]byte, binary.BigEndian.Uint16(header[3:]))
+	if _, err := io.ReadFull(server, record); err != nil {
+		t.Fatal(err)
+	}
+	return append(header, record...)
+}
+
+func TestParseClientHelloServerName(t *testing.T) {
+	hello := clientHello(t, "API.example.com")
+	name, ok := parseClientHelloServerName(hello)
+	if !ok || name != "api.example.com" {
+		t.Fatalf("expected api.example.com, got %q %v", name, ok)
+	}
+
+	if _, ok := parseClientHelloServerName(hello[:len(hello)-10]); ok {
+		t.Fatalf("expected a truncated ClientHello not to parse")
+	}
+	if _, ok := parseClientHelloServerName([]byte("GET / HTTP/1.1\r\n\r\n")); ok {
+		t.Fatalf("expected an HTTP request not to parse")
+	}
+}
+
+func TestParseHTTPHost(t *testing.T) {
+	tests := []struct {
+		request string
+		host    string
+		ok      bool
+	}{
+		{"GET / HTTP/1.1\r\nHost: api.example.com\r\n\r\n", "api.example.com", true},
+		{"POST /v1 HTTP/1.1\r\nhost: Example.com:8080\r\nContent-Length: 0\r\n\r\n", "example.com", true},
+		{"GET http://proxy.example.com/x HTTP/1.1\r\nHost: other.test\r\n\r\n", "proxy.example.com", true},
+		{"CONNECT api.example.com:443 HTTP/1.1\r\n\r\n", "api.example.com", true},
+		{"GET / HTTP/1.1\r\nHost: [2001:db8::1]:80\r\n\r\n", "2001:db8::1", true},
+		{"GET / HTTP/1.1\r\nHost: a.example.com\r\nHost: evil.test\r\n\r\n", "", false},
+		{"GET / HTTP/1.1\r\nHost: api.example.com\r\n", "", false},
+		{"SSH-2.0-OpenSSH_9.6\r\n\r\n", "", false},
+	}
+
+	for _, tt := range tests {
+		host, ok := parseHTTPHost([]byte(tt.request))
+		if ok != tt.ok || host != tt.host {
+			t.Fatalf("%q: expected %q %v, got %q %v", tt.request, tt.host, tt.ok, host, ok)
+		}
+	}
+}
+
+func TestFirstWriteHostAllowed(t *testing.T) {
+	const (
+		https   = 1000
+		sent    = 1001
+		udp     = 1002
+		file    = 1003
+		bufAddr = 0x1000
+	)
+	peer := netip.MustParseAddrPort("192.0.2.1:443")
+	fds := args.NewFdTable()
+	fds.Set(https, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_STREAM, Peer: peer})
+	fds.Set(sent, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_STREAM, Peer: peer, Sent: true})
+	fds.Set(udp, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_DGRAM, Peer: peer})
+	fds.Set(file, args.FdInfo{Type: args.FDFile, Flags: unix.O_RDONLY})
+
+	write := func(fd int, data []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Reader = fakeMemory{bufAddr: data}.read
+			s.Args[0] = SyscallArgument{Value: uintptr(fd)}
+			s.Args[1] = SyscallArgument{Value: bufAddr}
+			s.Args[2] = SyscallArgument{Value: uintptr(len(data))}
+			return IsWriteBufferAllowed(*s, true)
+		}
+	}
+	sendfile := func(s *Syscall) bool {
+		s.Args[0] = SyscallArgument{Value: https}
+		s.Args[1] = SyscallArgument{Value: file}
+		return IsSendfileAllowed(*s, true)
+	}
+	fastOpen := func(s *Syscall) bool {
+		s.Args[0] = SyscallArgument{Value: udp}
+		s.Args[3] = SyscallArgument{Value: unix.MSG_FASTOPEN}
+		return IsSendtoAllowed(*s, true)
+	}
+
+	client := runtime.NetworkConfig{NetworkAllowClient: true}
+	hosts := runtime.NetworkConfig{NetworkAllowClient: true, NetworkAllowedHosts: []string{"example.com"}}
+	tests := []struct {
+		name    string
+		check   func(s *Syscall) bool
+		network runtime.NetworkConfig
+		want    bool
+	}{
+		{"without allowlist", write(https, []byte("anything")), client, true},
+		{"tls allowed host", write(https, clientHello(t, "api.example.com")), hosts, true},
+		{"tls denied host", write(https, clientHello(t, "evil.test")), hosts, false},
+		{"http allowed host", write(https, []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")), hosts, true},
+		{"http denied host", write(https, []byte("GET / HTTP/1.1\r\nHost: evil.test\r\n\r\n")), hosts, false},
+		{"unknown protocol", write(https, []byte("SSH-2.0-OpenSSH_9.6\r\n")), hosts, false},
+		{"later write", write(sent, []byte("anything")), hosts, true},
+		{"udp", write(udp, []byte("anything")), hosts, true},
+		{"sendfile", sendfile, hosts, false},
+		{"sendfile without allowlist", sendfile, runtime.NetworkConfig{NetworkAllowClient: true}, true},
+		{"fast open", fastOpen, hosts, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config: &runtime.Config{
+					FsConfig:      runtime.FsConfig{FileSystemAllowRead: true},
+					NetworkConfig: tt.network,
+				},
+			}
+
+			if got := tt.check(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFirstWriteHostAllowedOnDup(t *testing.T) {
+	const bufAddr = 0x1000
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	raw, err := conn.(*net.TCPConn).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	// socket(); dup(); connect() of the original: the duplicate has no
+	// peer in the table.
+	dup := int32(raw.Fd())
+	fds := args.NewFdTable()
+	fds.Set(dup, args.FdInfo{Type: args.FDSocket, Family: unix.AF_INET, SockType: unix.SOCK_STREAM})
+
+	write := func(data []byte) bool {
+		s := Syscall{
+			TraceePID: os.Getpid(),
+			Fds:       fds,
+			Reader:    fakeMemory{bufAddr: data}.read,
+			Config:    &runtime.Config{NetworkConfig: runtime.NetworkConfig{NetworkAllowClient: true, NetworkAllowedHosts: []string{"example.com"}}},
+		}
+		s.Args[0] = SyscallArgument{Value: uintptr(dup)}
+		s.Args[1] = SyscallArgument{Value: bufAddr}
+		s.Args[2] = SyscallArgument{Value: uintptr(len(data))}
+		return IsWriteBufferAllowed(s, true)
+	}
+
+	if write(clientHello(t, "evil.test")) {
+		t.Fatalf("expected a denied host on a duplicated fd to be denied")
+	}
+	if write([]byte("GET / HTTP/1.1\r\nHost: evil.test\r\n\r\n")) {
+		t.Fatalf("expected a denied HTTP host on a duplicated fd to be denied")
+	}
+	if !write(clientHello(t, "api.example.com")) {
+		t.Fatalf("expected an allowed host on a duplicated fd to be allowed")
+	}
+	if info, _ := fds.Get(dup); info.Peer.String() != conn.RemoteAddr().String() {
+		t.Fatalf("expected the peer %s to be recorded, got %s", conn.RemoteAddr(), info.Peer)
+	}
+
+	// An fd missing from the table is looked up as well.
+	fds.Close(dup)
+	if write(clientHello(t, "evil.test")) {
+		t.Fatalf("expected a denied host on an untracked fd to be denied")
+	}
+}
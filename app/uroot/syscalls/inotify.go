@@ -0,0 +1,16 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import "golang.org/x/sys/unix"
+
+// IsInotifyAddWatchAllowed checks inotify_add_watch(fd, pathname, mask).
+// pathname is arg 1. It is not relative to fd, which is the inotify
+// instance. IN_DONT_FOLLOW keeps a symlink in the last component from being
+// followed.
+func IsInotifyAddWatchAllowed(s Syscall, isEnter bool) bool {
+	if s.Args[2].Uint()&unix.IN_DONT_FOLLOW != 0 {
+		return PathIsAllowedNoFollow(s, 1, -1)
+	}
+	return PathIsAllowed(s, 1, -1)
+}
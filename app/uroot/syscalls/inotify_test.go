@@ -0,0 +1,21 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsInotifyAddWatchAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsInotifyAddWatchAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: allowed}, ints: map[int]uintptr{0: 3, 2: unix.IN_MODIFY}, want: true},
+		{name: "outside", paths: map[int]string{1: outside}, ints: map[int]uintptr{0: 3, 2: unix.IN_MODIFY}, want: false},
+		{name: "symlink to outside", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: 3, 2: unix.IN_MODIFY}, want: false},
+		{name: "symlink not followed", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: 3, 2: unix.IN_MODIFY | unix.IN_DONT_FOLLOW}, want: true},
+	})
+}
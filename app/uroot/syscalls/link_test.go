@@ -3,6 +3,7 @@
 package syscalls
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -12,12 +13,15 @@ import (
 func TestLinkNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old")
 	newp := filepath.Join(other, "new")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7030)
 	b2 := uintptr(0x8038)
 	s.Args[0] = SyscallArgument{Value: b1}
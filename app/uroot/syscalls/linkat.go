@@ -2,20 +2,19 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
+import "golang.org/x/sys/unix"
 
 // IsLinkAtAllowed checks linkat(olddirfd, oldpath, newdirfd, newpath, flags).
 func IsLinkAtAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
-	if !PathIsAllowed(s, 1, 0) {
+	// oldpath is only dereferenced with AT_SYMLINK_FOLLOW
+	if !pathIsAllowed(s, 1, 0, s.Args[4].Int()&unix.AT_SYMLINK_FOLLOW != 0) {
 		return false
 	}
-	if !PathIsAllowed(s, 3, 2) {
+	if !PathIsAllowedNoFollow(s, 3, 2) {
 		return false
 	}
 	return true
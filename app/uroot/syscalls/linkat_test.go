@@ -4,6 +4,7 @@ package syscalls
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -12,12 +13,15 @@ import (
 
 func TestLinkAtAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old")
 	newp := filepath.Join(td, "new")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7200)
 	b2 := uintptr(0x8200)
 	// linkat(olddirfd, oldpath, newdirfd, newpath, flags)
@@ -53,12 +57,15 @@ func TestLinkAtAllowed(t *testing.T) {
 func TestLinkAtNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old")
 	newp := filepath.Join(other, "new")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7210)
 	b2 := uintptr(0x8210)
 	s.Args[1] = SyscallArgument{Value: b1}
@@ -0,0 +1,15 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsMknodAllowed checks mknod(pathname, mode, dev).
+// pathname is arg 0.
+func IsMknodAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 0, -1)
+}
+
+// IsMknodAtAllowed checks mknodat(dirfd, pathname, mode, dev).
+// pathname is arg 1, dirfd is arg 0.
+func IsMknodAtAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 1, 0)
+}
@@ -0,0 +1,26 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsMknodAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsMknodAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: filepath.Join(allowed, "fifo")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "fifo")}, want: false},
+	})
+}
+
+func TestIsMknodAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsMknodAtAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "fifo")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "fifo")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+	})
+}
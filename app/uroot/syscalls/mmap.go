@@ -0,0 +1,264 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapping is a memory mapping of a tracee as listed in /proc/<pid>/maps.
+type mapping struct {
+	Start, End uint64
+	// Shared is set for MAP_SHARED mappings, whose writes reach the file.
+	Shared bool
+	// Writable is set if the mapping currently allows writes.
+	Writable bool
+	// Inode is 0 for anonymous mappings.
+	Inode uint64
+	Path  string
+	// Anonymous is the size in kB of the pages that are not backed by the
+	// file, e.g. the copy-on-write pages of a private mapping that was
+	// written to. It is only listed in /proc/<pid>/smaps.
+	Anonymous uint64
+}
+
+// readMappings parses the memory mappings of pid.
+func readMappings(pid int) ([]mapping, error) {
+	return parseMappings(fmt.Sprintf("/proc/%d/maps", pid))
+}
+
+// readSmaps parses the memory mappings of pid including the size of their
+// anonymous pages. It is slower than readMappings, as the kernel walks the
+// page tables of every mapping.
+func readSmaps(pid int) ([]mapping, error) {
+	return parseMappings(fmt.Sprintf("/proc/%d/smaps", pid))
+}
+
+// parseMappings parses the maps or smaps file at path.
+func parseMappings(path string) ([]mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mappings []mapping
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// start-end perms offset dev inode [path]
+		fields := strings.SplitN(scanner.Text(), " ", 6)
+		if strings.HasSuffix(fields[0], ":") {
+			// attribute of the previous mapping in smaps, e.g.
+			// "Anonymous:        4 kB"
+			if fields[0] == "Anonymous:" && len(mappings) > 0 {
+				kB, err := strconv.ParseUint(strings.Fields(scanner.Text())[1], 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				mappings[len(mappings)-1].Anonymous = kB
+			}
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("malformed mapping %q", scanner.Text())
+		}
+		start, end, ok := strings.Cut(fields[0], "-")
+		if !ok || len(fields[1]) != 4 {
+			return nil, fmt.Errorf("malformed mapping %q", scanner.Text())
+		}
+		var m mapping
+		if m.Start, err = strconv.ParseUint(start, 16, 64); err != nil {
+			return nil, err
+		}
+		if m.End, err = strconv.ParseUint(end, 16, 64); err != nil {
+			return nil, err
+		}
+		if m.Inode, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+			return nil, err
+		}
+		m.Writable = fields[1][1] == 'w'
+		m.Shared = fields[1][3] == 's'
+		if len(fields) == 6 {
+			m.Path = strings.TrimLeft(fields[5], " ")
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, scanner.Err()
+}
+
+// isAnonymousSharedMemory returns true if path, as shown for an fd or a
+// mapping, names memory that is not backed by a file of the filesystem:
+// memfds, shared anonymous mappings and System V shared memory.
+func isAnonymousSharedMemory(path string) bool {
+	return strings.HasPrefix(path, "/memfd:") ||
+		path == "/dev/zero (deleted)" ||
+		strings.HasPrefix(path, "/SYSV")
+}
+
+// isSharedFileWriteAllowed decides whether the tracee may write to the file
+// at path through a shared mapping. The file must be writable under the
+// path policy, as writes to the mapping change it without a write syscall.
+func isSharedFileWriteAllowed(s Syscall, path string) bool {
+	if isAnonymousSharedMemory(path) {
+		return true
+	}
+	if !s.config().FileSystemAllowWrite {
+		fmt.Printf("shared writable mapping of %s is not allowed without file system write access\n", path)
+		return false
+	}
+	// Files that were removed after mapping them keep their old path.
+	path = strings.TrimSuffix(path, " (deleted)")
+	if !strings.HasPrefix(path, "/") {
+		fmt.Printf("shared writable mapping of %s is not allowed\n", path)
+		return false
+	}
+	return ResolvedPathIsAllowed(s, path)
+}
+
+// isSharedWritable returns true if a mapping with prot and flags writes
+// through to its file.
+func isSharedWritable(prot int, flags int) bool {
+	return prot&unix.PROT_WRITE != 0 && flags&unix.MAP_SHARED != 0
+}
+
+// traceeFdPath returns the path of the file referred to by fd of the
+// tracee, or "" if fd is not open.
+func traceeFdPath(s Syscall, fd int32) string {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok && info.Path != "" {
+			return info.Path
+		}
+	}
+	path, _ := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", s.TraceePID, fd))
+	return path
+}
+
+// isProtHardened checks prot for memory against the opt-in hardening of
+// the configuration. Memory must not be writable and executable at once, and
+// anonymous memory must not become executable after the startup window, as
+// both are how injected code is usually run.
+func isProtHardened(s Syscall, prot int, anonymous bool) bool {
+	c := s.config()
+	if !c.MemoryDenyWriteExecute {
+		return true
+	}
+	if prot&(unix.PROT_WRITE|unix.PROT_EXEC) == unix.PROT_WRITE|unix.PROT_EXEC {
+		fmt.Println("writable and executable memory is not allowed")
+		return false
+	}
+	if prot&unix.PROT_EXEC != 0 && anonymous && s.SessionAge >= c.MemoryAnonymousExecWindow {
+		fmt.Println("executable anonymous memory is not allowed")
+		return false
+	}
+	return true
+}
+
+// IsMmapAllowed checks mmap(addr, length, prot, flags, fd, offset). Shared
+// writable mappings of a file are only allowed if the file may be written.
+// Private mappings are copy-on-write and never change the file.
+func IsMmapAllowed(s Syscall, isEnter bool) bool {
+	prot, flags := int(s.Args[2].Int()), int(s.Args[3].Int())
+	anonymous := flags&unix.MAP_ANONYMOUS != 0
+
+	var path string
+	if !anonymous {
+		path = traceeFdPath(s, s.Args[4].Int())
+	}
+	if !isProtHardened(s, prot, anonymous || isAnonymousSharedMemory(path)) {
+		return false
+	}
+
+	if anonymous || !isSharedWritable(prot, flags) {
+		return true
+	}
+	if path == "" {
+		// The kernel fails with EBADF
+		return true
+	}
+	return isSharedFileWriteAllowed(s, path)
+}
+
+// isSharedRangeAllowed checks the shared file mappings of the tracee that
+// overlap [addr, addr+length). Read-only mappings are skipped unless
+// makeWritable is set.
+func isSharedRangeAllowed(s Syscall, addr uint64, length uint64, makeWritable bool) bool {
+	mappings, err := readMappings(s.TraceePID)
+	if err != nil {
+		fmt.Printf("unable to read mappings of pid %d: %s\n", s.TraceePID, err.Error())
+		return false
+	}
+	for _, m := range mappings {
+		if m.End <= addr || m.Start >= addr+length {
+			continue
+		}
+		if !m.Shared || m.Inode == 0 || (!m.Writable && !makeWritable) {
+			continue
+		}
+		if !isSharedFileWriteAllowed(s, m.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAnonymousRange returns true if any mapping of the tracee that overlaps
+// [addr, addr+length) holds anonymous memory. Besides anonymous mappings,
+// this includes private file mappings that are or were writable: their
+// written pages are copies of the file with whatever the tracee wrote to
+// them, e.g. injected code.
+func isAnonymousRange(s Syscall, addr uint64, length uint64) (bool, error) {
+	mappings, err := readSmaps(s.TraceePID)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range mappings {
+		if m.End <= addr || m.Start >= addr+length {
+			continue
+		}
+		if m.Inode == 0 || isAnonymousSharedMemory(m.Path) {
+			return true, nil
+		}
+		if !m.Shared && (m.Writable || m.Anonymous > 0) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsMprotectAllowed checks mprotect(addr, length, prot) and
+// pkey_mprotect(addr, length, prot, pkey). Adding write access to a shared
+// mapping of a file is checked like mmap.
+func IsMprotectAllowed(s Syscall, isEnter bool) bool {
+	addr, length := uint64(s.Args[0].Value), uint64(s.Args[1].Value)
+	prot := int(s.Args[2].Int())
+
+	anonymous := false
+	if s.config().MemoryDenyWriteExecute && prot&unix.PROT_EXEC != 0 {
+		var err error
+		if anonymous, err = isAnonymousRange(s, addr, length); err != nil {
+			fmt.Printf("unable to read mappings of pid %d: %s\n", s.TraceePID, err.Error())
+			return false
+		}
+	}
+	if !isProtHardened(s, prot, anonymous) {
+		return false
+	}
+
+	if prot&unix.PROT_WRITE == 0 {
+		return true
+	}
+	return isSharedRangeAllowed(s, addr, length, true)
+}
+
+// IsRemapFilePagesAllowed checks remap_file_pages(addr, size, prot, pgoff,
+// flags). It rearranges the pages of a shared file mapping, so writable
+// mappings in the range are checked like mmap.
+func IsRemapFilePagesAllowed(s Syscall, isEnter bool) bool {
+	return isSharedRangeAllowed(s, uint64(s.Args[0].Value), uint64(s.Args[1].Value), false)
+}
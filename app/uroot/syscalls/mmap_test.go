@@ -0,0 +1,269 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+func TestIsMmapAllowed(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		allowed = 1000
+		other   = 1001
+		memfd   = 1002
+	)
+	fds := args.NewFdTable()
+	fds.Set(allowed, args.FdInfo{Type: args.FDFile, Path: filepath.Join(td, "file")})
+	fds.Set(other, args.FdInfo{Type: args.FDFile, Path: "/var/tmp/other"})
+	fds.Set(memfd, args.FdInfo{Type: args.FDFile, Path: "/memfd:buffer (deleted)"})
+
+	readWrite := runtime.FsConfig{FileSystemAllowRead: true, FileSystemAllowWrite: true, FileSystemAllowedPaths: []string{td}}
+	readOnly := runtime.FsConfig{FileSystemAllowRead: true, FileSystemAllowedPaths: []string{td}}
+
+	const (
+		rw = unix.PROT_READ | unix.PROT_WRITE
+		ro = unix.PROT_READ
+	)
+	tests := []struct {
+		name  string
+		prot  int
+		flags int
+		fd    int
+		fs    runtime.FsConfig
+		want  bool
+	}{
+		{"shared writable allowed file", rw, unix.MAP_SHARED, allowed, readWrite, true},
+		{"shared writable other file", rw, unix.MAP_SHARED, other, readWrite, false},
+		{"shared writable without write access", rw, unix.MAP_SHARED, allowed, readOnly, false},
+		{"shared validate writable other file", rw, unix.MAP_SHARED_VALIDATE, other, readWrite, false},
+		{"shared read-only other file", ro, unix.MAP_SHARED, other, readOnly, true},
+		{"private writable other file", rw, unix.MAP_PRIVATE, other, readOnly, true},
+		{"shared anonymous", rw, unix.MAP_SHARED | unix.MAP_ANONYMOUS, -1, readOnly, true},
+		{"shared writable memfd", rw, unix.MAP_SHARED, memfd, readOnly, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{FsConfig: tt.fs},
+			}
+			s.Args[2] = SyscallArgument{Value: uintptr(tt.prot)}
+			s.Args[3] = SyscallArgument{Value: uintptr(tt.flags)}
+			s.Args[4] = SyscallArgument{Value: uintptr(tt.fd)}
+
+			if got := IsMmapAllowed(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsMprotectAllowed(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(td, "file")
+	if err := os.WriteFile(path, make([]byte, os.Getpagesize()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	shared, err := unix.Mmap(int(f.Fd()), 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(shared)
+	private, err := unix.Mmap(int(f.Fd()), 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(private)
+
+	tests := []struct {
+		name    string
+		mapping []byte
+		prot    int
+		fs      runtime.FsConfig
+		want    bool
+	}{
+		{"add write to shared mapping of allowed file", shared, unix.PROT_READ | unix.PROT_WRITE, runtime.FsConfig{FileSystemAllowWrite: true, FileSystemAllowedPaths: []string{td}}, true},
+		{"add write to shared mapping of other file", shared, unix.PROT_READ | unix.PROT_WRITE, runtime.FsConfig{FileSystemAllowWrite: true, FileSystemAllowedPaths: []string{"/nonexistent"}}, false},
+		{"add write without write access", shared, unix.PROT_WRITE, runtime.FsConfig{FileSystemAllowedPaths: []string{td}}, false},
+		{"remove write from shared mapping", shared, unix.PROT_READ, runtime.FsConfig{}, true},
+		{"add write to private mapping", private, unix.PROT_READ | unix.PROT_WRITE, runtime.FsConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Config:    &runtime.Config{FsConfig: tt.fs},
+			}
+			s.Args[0] = SyscallArgument{Value: uintptr(unsafe.Pointer(&tt.mapping[0]))}
+			s.Args[1] = SyscallArgument{Value: uintptr(len(tt.mapping))}
+			s.Args[2] = SyscallArgument{Value: uintptr(tt.prot)}
+
+			if got := IsMprotectAllowed(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryHardening(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(td, "lib.so")
+	if err := os.WriteFile(path, make([]byte, os.Getpagesize()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	memfd, err := unix.MemfdCreate("payload", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(memfd)
+
+	anonymous, err := unix.Mmap(-1, 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(anonymous)
+	file, err := unix.Mmap(int(f.Fd()), 0, os.Getpagesize(), unix.PROT_READ, unix.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(file)
+	writable, err := unix.Mmap(int(f.Fd()), 0, os.Getpagesize(), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(writable)
+	// copy-on-write pages with code written to them, then made read-only
+	written, err := unix.Mmap(int(f.Fd()), 0, os.Getpagesize(), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(written)
+	written[0] = 0xc3
+	if err := unix.Mprotect(written, unix.PROT_READ); err != nil {
+		t.Fatal(err)
+	}
+
+	mmap := func(prot, flags, fd int) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[2] = SyscallArgument{Value: uintptr(prot)}
+			s.Args[3] = SyscallArgument{Value: uintptr(flags)}
+			s.Args[4] = SyscallArgument{Value: uintptr(fd)}
+			return IsMmapAllowed(*s, true)
+		}
+	}
+	mprotect := func(mapping []byte, prot int) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[0] = SyscallArgument{Value: uintptr(unsafe.Pointer(&mapping[0]))}
+			s.Args[1] = SyscallArgument{Value: uintptr(len(mapping))}
+			s.Args[2] = SyscallArgument{Value: uintptr(prot)}
+			return IsMprotectAllowed(*s, true)
+		}
+	}
+	execveat := func(fd int) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			base := uintptr(0x5000)
+			s.Reader = makeReaderFor("", base)
+			s.Args[0] = SyscallArgument{Value: uintptr(fd)}
+			s.Args[1] = SyscallArgument{Value: base}
+			s.Args[4] = SyscallArgument{Value: uintptr(unix.AT_EMPTY_PATH)}
+			return IsExecveAtAllowed(*s, true)
+		}
+	}
+
+	execve := func(path string) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			base := uintptr(0x5000)
+			s.Reader = makeReaderFor(path, base)
+			s.Args[0] = SyscallArgument{Value: base}
+			return IsExecveAllowed(*s, true)
+		}
+	}
+	execveatPath := func(path string) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			base := uintptr(0x5000)
+			s.Reader = makeReaderFor(path, base)
+			s.Args[0] = SyscallArgument{Value: atFdcwd}
+			s.Args[1] = SyscallArgument{Value: base}
+			return IsExecveAtAllowed(*s, true)
+		}
+	}
+	memfdPath := fmt.Sprintf("/proc/self/fd/%d", memfd)
+
+	const (
+		rx  = unix.PROT_READ | unix.PROT_EXEC
+		rwx = unix.PROT_READ | unix.PROT_WRITE | unix.PROT_EXEC
+	)
+	anon := unix.MAP_PRIVATE | unix.MAP_ANONYMOUS
+	tests := []struct {
+		name   string
+		check  func(s *Syscall) bool
+		memory runtime.MemoryConfig
+		want   bool
+	}{
+		{"mmap rwx without hardening", mmap(rwx, anon, -1), runtime.MemoryConfig{}, true},
+		{"mmap rwx", mmap(rwx, anon, -1), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mmap rwx within window", mmap(rwx, anon, -1), runtime.MemoryConfig{MemoryDenyWriteExecute: true, MemoryAnonymousExecWindow: time.Hour}, false},
+		{"mmap anonymous exec", mmap(rx, anon, -1), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mmap anonymous exec within window", mmap(rx, anon, -1), runtime.MemoryConfig{MemoryDenyWriteExecute: true, MemoryAnonymousExecWindow: time.Hour}, true},
+		{"mmap file exec", mmap(rx, unix.MAP_PRIVATE, int(f.Fd())), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, true},
+		{"mmap memfd exec", mmap(rx, unix.MAP_PRIVATE, memfd), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mprotect anonymous exec", mprotect(anonymous, rx), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mprotect anonymous rwx within window", mprotect(anonymous, rwx), runtime.MemoryConfig{MemoryDenyWriteExecute: true, MemoryAnonymousExecWindow: time.Hour}, false},
+		{"mprotect file exec", mprotect(file, rx), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, true},
+		{"mprotect writable private file exec", mprotect(writable, rx), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mprotect written private file exec", mprotect(written, rx), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"mprotect written private file exec within window", mprotect(written, rx), runtime.MemoryConfig{MemoryDenyWriteExecute: true, MemoryAnonymousExecWindow: time.Hour}, true},
+		{"execveat memfd without hardening", execveat(memfd), runtime.MemoryConfig{}, true},
+		{"execveat memfd", execveat(memfd), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"execveat file", execveat(int(f.Fd())), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, true},
+		{"execve memfd link without hardening", execve(memfdPath), runtime.MemoryConfig{}, true},
+		{"execve memfd link", execve(memfdPath), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"execveat memfd link", execveatPath(memfdPath), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, false},
+		{"execve file", execve(path), runtime.MemoryConfig{MemoryDenyWriteExecute: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Config:    &runtime.Config{MemoryConfig: tt.memory},
+			}
+			if got := tt.check(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
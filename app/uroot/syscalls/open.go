@@ -3,7 +3,6 @@
 package syscalls
 
 import (
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
@@ -20,18 +19,28 @@ func IsOpenReadOnly(s Syscall, isEnter bool) bool {
 }
 
 func IsOpenAllowed(s Syscall, isEnter bool) bool {
-	readAllowed := runtime.Get().FileSystemAllowRead
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	flags := int(s.Args[1].Uint())
+	readAllowed := s.config().FileSystemAllowRead
+	writeAllowed := s.config().FileSystemAllowWrite
 
 	isReadOnlySyscall := IsOpenReadOnly(s, isEnter)
 
 	if isReadOnlySyscall && readAllowed {
-		return PathIsAllowed(s, 0, -1)
+		return pathIsAllowed(s, 0, -1, openFollowsLink(flags))
 	}
 
 	if !isReadOnlySyscall && writeAllowed {
-		return PathIsAllowed(s, 0, -1)
+		return pathIsAllowed(s, 0, -1, openFollowsLink(flags))
 	}
 
 	return false
 }
+
+// openFollowsLink returns true if open with the given flags follows a
+// symlink in the last component of the path.
+func openFollowsLink(flags int) bool {
+	if flags&unix.O_NOFOLLOW != 0 {
+		return false
+	}
+	return flags&(unix.O_CREAT|unix.O_EXCL) != unix.O_CREAT|unix.O_EXCL
+}
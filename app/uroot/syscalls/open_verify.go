@@ -0,0 +1,38 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IsOpenedFdAllowed re-checks the file the tracee opened as fd against the
+// allowed paths after open, openat or openat2 returned. The path argument is
+// read from tracee memory at syscall entry, so another thread of the tracee
+// might have replaced it before the kernel read it. The fd tells which file
+// was actually opened.
+func IsOpenedFdAllowed(s Syscall, fd int) bool {
+	if len(s.config().FileSystemAllowedPaths) == 0 {
+		// No path-level restriction configured
+		return true
+	}
+
+	fdPath := fmt.Sprintf("/proc/%d/fd/%d", s.TraceePID, fd)
+	resolved, err := os.Readlink(fdPath)
+	if err != nil {
+		fmt.Printf("unable to verify fd %d of pid %d: %s\n", fd, s.TraceePID, err.Error())
+		return false
+	}
+
+	// Files that were removed after opening them keep their old path.
+	resolved = strings.TrimSuffix(resolved, " (deleted)")
+	if !strings.HasPrefix(resolved, "/") {
+		// pipes, sockets and anonymous inodes cannot be opened by path
+		fmt.Printf("fd %d of pid %d is %s instead of a file\n", fd, s.TraceePID, resolved)
+		return false
+	}
+
+	return ResolvedPathIsAllowed(s, resolved)
+}
@@ -0,0 +1,68 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+)
+
+func TestIsOpenedFdAllowed(t *testing.T) {
+	allowedDir := t.TempDir()
+	otherDir := t.TempDir()
+
+	allowedFile, err := os.Create(filepath.Join(allowedDir, "allowed.txt"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer allowedFile.Close()
+	otherFile, err := os.Create(filepath.Join(otherDir, "other.txt"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer otherFile.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	deleted, err := os.Create(filepath.Join(allowedDir, "deleted.txt"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer deleted.Close()
+	if err := os.Remove(deleted.Name()); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		allowedPaths []string
+		fd           int
+		want         bool
+	}{
+		{"no path restriction", nil, int(otherFile.Fd()), true},
+		{"file in allowed dir", []string{allowedDir}, int(allowedFile.Fd()), true},
+		{"file outside allowed dir", []string{allowedDir}, int(otherFile.Fd()), false},
+		{"deleted file in allowed dir", []string{allowedDir}, int(deleted.Fd()), true},
+		{"pipe", []string{allowedDir}, int(r.Fd()), false},
+		{"closed fd", []string{allowedDir}, 4096, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Config:    &runtime.Config{FsConfig: runtime.FsConfig{FileSystemAllowedPaths: tt.allowedPaths}},
+			}
+			if got := IsOpenedFdAllowed(s, tt.fd); got != tt.want {
+				t.Fatalf("IsOpenedFdAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package syscalls
 
 import (
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
@@ -17,16 +16,17 @@ func IsOpenAtReadOnly(s Syscall, isEnter bool) bool {
 }
 
 func IsOpenAtAllowed(s Syscall, isEnter bool) bool {
-	readAllowed := runtime.Get().FileSystemAllowRead
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	flags := int(s.Args[2].Uint())
+	readAllowed := s.config().FileSystemAllowRead
+	writeAllowed := s.config().FileSystemAllowWrite
 	isReadOnlySyscall := IsOpenAtReadOnly(s, isEnter)
 
 	if isReadOnlySyscall && readAllowed {
-		return PathIsAllowed(s, 1, 0)
+		return pathIsAllowed(s, 1, 0, openFollowsLink(flags))
 	}
 
 	if !isReadOnlySyscall && writeAllowed {
-		return PathIsAllowed(s, 1, 0)
+		return pathIsAllowed(s, 1, 0, openFollowsLink(flags))
 	}
 
 	return false
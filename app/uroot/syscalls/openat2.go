@@ -3,7 +3,6 @@
 package syscalls
 
 import (
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
@@ -32,8 +31,8 @@ func IsOpenAt2ReadOnly(s Syscall, isEnter bool) bool {
 }
 
 func IsOpenAt2Allowed(s Syscall, isEnter bool) bool {
-	readAllowed := runtime.Get().FileSystemAllowRead
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	readAllowed := s.config().FileSystemAllowRead
+	writeAllowed := s.config().FileSystemAllowWrite
 
 	isReadOnlySyscall := IsOpenAt2ReadOnly(s, isEnter)
 	if isReadOnlySyscall && readAllowed {
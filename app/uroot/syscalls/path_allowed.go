@@ -4,11 +4,9 @@ package syscalls
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
@@ -32,9 +30,22 @@ func readPath(s Syscall, addr Addr, maxLen int) (string, error) {
 // PathIsAllowed checks whether the path argument (at pathArgIndex) of the
 // provided Syscall falls under any of the configured allowed paths. If
 // dirfdArgIndex >= 0 it is used to resolve relative paths (as in openat).
+// The path is resolved in the tracee's root, following symlinks including
+// the last component, so the real target of the syscall is checked.
 // If runtime config's FileSystemAllowedPaths is empty, PathIsAllowed returns true.
 func PathIsAllowed(s Syscall, pathArgIndex int, dirfdArgIndex int) bool {
-	allowed := runtime.Get().FileSystemAllowedPaths
+	return pathIsAllowed(s, pathArgIndex, dirfdArgIndex, true)
+}
+
+// PathIsAllowedNoFollow is like PathIsAllowed, but does not follow a symlink
+// in the last component. It is used for syscalls that act on the link
+// itself, e.g. unlink or rename.
+func PathIsAllowedNoFollow(s Syscall, pathArgIndex int, dirfdArgIndex int) bool {
+	return pathIsAllowed(s, pathArgIndex, dirfdArgIndex, false)
+}
+
+func pathIsAllowed(s Syscall, pathArgIndex int, dirfdArgIndex int, followLast bool) bool {
+	allowed := s.config().FileSystemAllowedPaths
 	if len(allowed) == 0 {
 		// No path-level restriction configured
 		return true
@@ -46,49 +57,33 @@ func PathIsAllowed(s Syscall, pathArgIndex int, dirfdArgIndex int) bool {
 		return false
 	}
 
-	// Resolve to absolute path (handle relative paths and dirfd)
-	var absPath string
-	if strings.HasPrefix(path, "/") {
-		absPath = filepath.Clean(path)
-	} else {
-		// Need to resolve based on dirfd (if provided) or tracee cwd
-		var base string
-		if dirfdArgIndex >= 0 {
-			dirfd := s.Args[dirfdArgIndex].Int()
-			if dirfd == unix.AT_FDCWD {
-				// tracee current working directory
-				cwdPath := fmt.Sprintf("/proc/%d/cwd", s.TraceePID)
-				if resolved, err := os.Readlink(cwdPath); err == nil {
-					base = resolved
-				} else {
-					return false
-				}
-			} else {
-				fdPath := fmt.Sprintf("/proc/%d/fd/%d", s.TraceePID, dirfd)
-				if resolved, err := os.Readlink(fdPath); err == nil {
-					base = resolved
-				} else {
-					return false
-				}
-			}
-		} else {
-			// No dirfd provided; interpret relative to tracee cwd
-			cwdPath := fmt.Sprintf("/proc/%d/cwd", s.TraceePID)
-			if resolved, err := os.Readlink(cwdPath); err == nil {
-				base = resolved
-			} else {
-				return false
-			}
-		}
-
-		joined := filepath.Join(base, path)
-		absPath = filepath.Clean(joined)
+	// Without dirfd, relative paths are interpreted relative to tracee cwd
+	dirfd := unix.AT_FDCWD
+	if dirfdArgIndex >= 0 {
+		dirfd = int(s.Args[dirfdArgIndex].Int())
+	}
+	resolved, err := resolveTraceePath(s.TraceePID, dirfd, path, followLast)
+	if err != nil {
+		fmt.Printf("unable to resolve path %s: %s\n", path, err.Error())
+		return false
 	}
 
 	// If the path doesn't exist yet (e.g., create), check parent directory
-	checkPath := absPath
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		checkPath = filepath.Dir(absPath)
+	checkPath := resolved.Path
+	if !resolved.Exists {
+		checkPath = filepath.Dir(resolved.Path)
+	}
+
+	return ResolvedPathIsAllowed(s, checkPath)
+}
+
+// ResolvedPathIsAllowed checks whether the absolute and clean path falls
+// under any of the configured allowed paths. If runtime config's
+// FileSystemAllowedPaths is empty, ResolvedPathIsAllowed returns true.
+func ResolvedPathIsAllowed(s Syscall, checkPath string) bool {
+	allowed := s.config().FileSystemAllowedPaths
+	if len(allowed) == 0 {
+		return true
 	}
 
 	for _, a := range allowed {
@@ -113,3 +108,17 @@ func PathIsAllowed(s Syscall, pathArgIndex int, dirfdArgIndex int) bool {
 
 	return false
 }
+
+// PathAtIsAllowed is like PathIsAllowed for *at syscalls that take flags.
+// AT_SYMLINK_NOFOLLOW keeps a symlink in the last component from being
+// followed. With AT_EMPTY_PATH and an empty path, the syscall operates on
+// dirfd itself, which the tracee already holds open.
+func PathAtIsAllowed(s Syscall, pathArgIndex int, dirfdArgIndex int, flags int) bool {
+	if flags&unix.AT_EMPTY_PATH != 0 {
+		path, err := readPath(s, s.Args[pathArgIndex].Pointer(), 1)
+		if err == nil && path == "" {
+			return true
+		}
+	}
+	return pathIsAllowed(s, pathArgIndex, dirfdArgIndex, flags&unix.AT_SYMLINK_NOFOLLOW == 0)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/utils"
+	"golang.org/x/sys/unix"
 )
 
 func makeReaderFor(path string, baseAddr uintptr) func(Addr, interface{}) (int, error) {
@@ -31,15 +32,18 @@ func makeReaderFor(path string, baseAddr uintptr) func(Addr, interface{}) (int,
 func TestPathIsAllowedAbsolute(t *testing.T) {
 	// create a temp dir to allow
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
 
 	path := filepath.Join(td, "file.txt")
 	var s Syscall
+	s.Config = conf
 	base := uintptr(0x1000)
 	// set path argument at arg 0
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
-	// TraceePID doesn't matter for absolute paths
+	// absolute paths are resolved in the root of the tracee
+	s.TraceePID = os.Getpid()
 	allowed := PathIsAllowed(s, 0, -1)
 	if !allowed {
 		t.Fatalf("expected allowed for path %s", path)
@@ -50,10 +54,13 @@ func TestPathIsNotAllowedAbsolute(t *testing.T) {
 	// allowed path is /tmp/allowed, we test with another dir
 	td := t.TempDir()
 	another := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
 
 	path := filepath.Join(another, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x2000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -67,7 +74,8 @@ func TestPathIsNotAllowedAbsolute(t *testing.T) {
 func TestPathIsAllowedRelativeWithDirfd(t *testing.T) {
 	// allowed to the temp dir
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
 
 	// create a file relative path inside td
 	relPath := "sub/file.txt"
@@ -81,6 +89,7 @@ func TestPathIsAllowedRelativeWithDirfd(t *testing.T) {
 	fd := int(f.Fd())
 
 	var s Syscall
+	s.Config = conf
 	base := uintptr(0x3000)
 	// dirfd at arg 0
 	s.Args[0] = SyscallArgument{Value: uintptr(fd)}
@@ -100,10 +109,13 @@ func TestPathIsAllowedRelativeWithDirfd(t *testing.T) {
 func TestPathIsAllowedNewFileUnderAllowedParent(t *testing.T) {
 	// allowed root
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
 
 	path := filepath.Join(td, "newfile-that-does-not-exist.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x4000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -118,10 +130,13 @@ func TestPathIsNotAllowedParentWhenOnlyChildAllowed(t *testing.T) {
 	// directory (/etc) should NOT be allowed for checks such as access().
 	td := t.TempDir()
 	child := filepath.Join(td, "childdir")
-	runtime.Get().FileSystemAllowedPaths = []string{child}
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{child}
 
 	path := td
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x4500)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -130,3 +145,72 @@ func TestPathIsNotAllowedParentWhenOnlyChildAllowed(t *testing.T) {
 		t.Fatalf("expected parent path %s NOT to be allowed when only child %s is allowed", path, child)
 	}
 }
+
+// atFdcwd is AT_FDCWD as passed in a syscall argument.
+var atFdcwd = func() uintptr {
+	fd := unix.AT_FDCWD
+	return uintptr(fd)
+}()
+
+// pathSyscallTest describes a path-taking syscall of the test process.
+// Pointer arguments at the indices of paths point to the given paths, ints
+// sets the other arguments.
+type pathSyscallTest struct {
+	name  string
+	paths map[int]string
+	ints  map[int]uintptr
+	want  bool
+}
+
+// pathFixture allows a temp dir with a file and a symlink to a file in
+// another, not allowed temp dir. It returns both dirs and the policy
+// allowing the first.
+func pathFixture(t *testing.T) (allowed string, outside string, conf *runtime.Config) {
+	allowed = t.TempDir()
+	outside = t.TempDir()
+	conf = &runtime.Config{FsConfig: runtime.FsConfig{FileSystemAllowedPaths: []string{allowed}}}
+
+	for _, dir := range []string{allowed, outside} {
+		if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "file"), filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+	return allowed, outside, conf
+}
+
+func runPathSyscallTests(t *testing.T, conf *runtime.Config, check func(s Syscall, isEnter bool) bool, tests []pathSyscallTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{TraceePID: os.Getpid(), Config: conf}
+			strs := map[Addr][]byte{}
+			for i, p := range tt.paths {
+				base := Addr(0x10000 * (i + 1))
+				s.Args[i] = SyscallArgument{Value: uintptr(base)}
+				strs[base] = []byte(p + "\x00")
+			}
+			for i, v := range tt.ints {
+				s.Args[i] = SyscallArgument{Value: v}
+			}
+			s.Reader = func(addr Addr, v interface{}) (int, error) {
+				bb, ok := v.(*[1]byte)
+				if !ok {
+					return 0, fmt.Errorf("unsupported v type")
+				}
+				b := strs[addr&^0xffff]
+				off := int(addr & 0xffff)
+				if off >= len(b) {
+					return 0, fmt.Errorf("out of range read: %d", off)
+				}
+				bb[0] = b[off]
+				return 1, nil
+			}
+
+			if got := check(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
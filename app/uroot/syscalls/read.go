@@ -5,34 +5,33 @@ package syscalls
 import (
 	"fmt"
 
-	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot/syscalls/args"
 )
 
 // IsReadAllowed decides allowance for read-like syscalls using fd context.
-// Unified signature: derive fd traits via args helpers using Syscall.TraceePID.
+// Unified signature: the fd type comes from the shadow fd table, or /proc.
 func IsReadAllowed(s Syscall, isEnter bool) bool {
-	fd := s.Args[0].Int()
-	isStdStream := args.IsStandardStream(fd)
+	return isReadFdAllowed(s, s.Args[0].Int())
+}
+
+// isReadFdAllowed decides whether the tracee may read from fd.
+func isReadFdAllowed(s Syscall, fd int32) bool {
+	isStdStream := s.IsStandardStream(fd)
 	if isStdStream {
 		return true
 	}
-	isSocket := args.IsSocket(s.TraceePID, fd)
-	if (runtime.Get().NetworkAllowServer || runtime.Get().NetworkAllowClient || runtime.Get().LocalSocketsAllow) && isSocket {
-		return true
-	}
-	isFile := args.IsFile(s.TraceePID, fd)
-	if runtime.Get().FileSystemAllowRead && isFile {
-		return true
-	}
-	isPipe := args.IsPipe(s.TraceePID, fd)
-	if isPipe {
+
+	switch fdType := s.FdType(fd); fdType {
+	case args.FDSocket:
+		return s.config().NetworkAllowServer || s.config().NetworkAllowClient || s.config().NetworkAllowLoopback || s.config().LocalSocketsAllow
+	case args.FDFile:
+		return s.config().FileSystemAllowRead
+	case args.FDPipe:
 		return true
-	}
-	isEventFd := args.FdType(s.TraceePID, fd) == args.FDAnonEvent
-	if isEventFd {
-		println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", "write", fd, isEventFd))
+	case args.FDAnonEvent:
+		println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", "read", fd, true))
 		return true
 	}
+
 	return false
 }
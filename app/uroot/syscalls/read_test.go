@@ -0,0 +1,43 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
+func TestIsReadAllowedUsesFdTable(t *testing.T) {
+	fds := args.NewFdTable()
+	// fd 1000 is not open in /proc, only the shadow table knows it
+	fds.Set(1000, args.FdInfo{Type: args.FDFile, Path: "/etc/hostname"})
+	fds.Set(1001, args.FdInfo{Type: args.FDSocket})
+
+	tests := []struct {
+		name string
+		fd   uintptr
+		want bool
+	}{
+		{"file", 1000, true},
+		{"socket without network", 1001, false},
+		{"unknown fd", 1002, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{FsConfig: runtime.FsConfig{FileSystemAllowRead: true}},
+			}
+			s.Args[0] = SyscallArgument{Value: tt.fd}
+
+			if got := IsReadAllowed(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsReadlinkAllowed checks readlink(pathname, buf, bufsiz). The link itself
+// is read, so its last component is not followed.
+// pathname is arg 0.
+func IsReadlinkAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 0, -1)
+}
+
+// IsReadlinkAtAllowed checks readlinkat(dirfd, pathname, buf, bufsiz).
+// pathname is arg 1, dirfd is arg 0.
+func IsReadlinkAtAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 1, 0)
+}
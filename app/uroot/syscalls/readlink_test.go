@@ -0,0 +1,26 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsReadlinkAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsReadlinkAllowed, []pathSyscallTest{
+		{name: "symlink in allowed dir", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+	})
+}
+
+func TestIsReadlinkAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsReadlinkAtAllowed, []pathSyscallTest{
+		{name: "symlink in allowed dir", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+	})
+}
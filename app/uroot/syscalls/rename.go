@@ -2,21 +2,17 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
-
 // IsRenameAllowed checks rename(oldpath, newpath).
 func IsRenameAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
 
-	if !PathIsAllowed(s, 0, -1) {
+	if !PathIsAllowedNoFollow(s, 0, -1) {
 		return false
 	}
-	if !PathIsAllowed(s, 1, -1) {
+	if !PathIsAllowedNoFollow(s, 1, -1) {
 		return false
 	}
 	return true
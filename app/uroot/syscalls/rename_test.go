@@ -4,6 +4,7 @@ package syscalls
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -12,12 +13,15 @@ import (
 
 func TestRenameAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old.txt")
 	newp := filepath.Join(td, "new.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x6000)
 	b2 := uintptr(0x7000)
 	s.Args[0] = SyscallArgument{Value: b1}
@@ -50,12 +54,15 @@ func TestRenameAllowed(t *testing.T) {
 func TestRenameNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old.txt")
 	newp := filepath.Join(other, "new.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7010)
 	b2 := uintptr(0x8010)
 	s.Args[0] = SyscallArgument{Value: b1}
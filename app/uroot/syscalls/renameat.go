@@ -2,20 +2,16 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
-
 // IsRenameAtAllowed checks renameat(olddirfd, oldpath, newdirfd, newpath).
 func IsRenameAtAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
-	if !PathIsAllowed(s, 1, 0) {
+	if !PathIsAllowedNoFollow(s, 1, 0) {
 		return false
 	}
-	if !PathIsAllowed(s, 3, 2) {
+	if !PathIsAllowedNoFollow(s, 3, 2) {
 		return false
 	}
 	return true
@@ -0,0 +1,13 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsRenameAt2Allowed checks renameat2(olddirfd, oldpath, newdirfd, newpath, flags).
+// Both paths are renamed themselves, so symlinks in their last component are
+// not followed.
+func IsRenameAt2Allowed(s Syscall, isEnter bool) bool {
+	if !PathIsAllowedNoFollow(s, 1, 0) {
+		return false
+	}
+	return PathIsAllowedNoFollow(s, 3, 2)
+}
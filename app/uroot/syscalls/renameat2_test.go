@@ -0,0 +1,20 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsRenameAt2Allowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+	dirfds := map[int]uintptr{0: atFdcwd, 2: atFdcwd}
+
+	runPathSyscallTests(t, conf, IsRenameAt2Allowed, []pathSyscallTest{
+		{name: "within allowed", paths: map[int]string{1: filepath.Join(allowed, "file"), 3: filepath.Join(allowed, "new")}, ints: dirfds, want: true},
+		{name: "symlink itself", paths: map[int]string{1: filepath.Join(allowed, "link"), 3: filepath.Join(allowed, "new")}, ints: dirfds, want: true},
+		{name: "from outside", paths: map[int]string{1: filepath.Join(outside, "file"), 3: filepath.Join(allowed, "new")}, ints: dirfds, want: false},
+		{name: "to outside", paths: map[int]string{1: filepath.Join(allowed, "file"), 3: filepath.Join(outside, "new")}, ints: dirfds, want: false},
+	})
+}
@@ -4,6 +4,7 @@ package syscalls
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -12,12 +13,15 @@ import (
 
 func TestRenameAtAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old.txt")
 	newp := filepath.Join(td, "new.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x6100)
 	b2 := uintptr(0x7100)
 	// renameat(olddirfd, oldpath, newdirfd, newpath)
@@ -53,12 +57,15 @@ func TestRenameAtAllowed(t *testing.T) {
 func TestRenameAtNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	old := filepath.Join(td, "old.txt")
 	newp := filepath.Join(other, "new.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7110)
 	b2 := uintptr(0x8110)
 	// renameat(olddirfd, oldpath, newdirfd, newpath)
@@ -0,0 +1,203 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSymlinks is the number of symlinks the kernel follows while resolving
+// a single path before failing with ELOOP.
+const maxSymlinks = 40
+
+// procRootIno is the inode number of the root directory of a procfs.
+const procRootIno = 1
+
+// resolvedPath is a path of a tracee resolved to the file the kernel would
+// operate on, as seen by the gatekeeper.
+type resolvedPath struct {
+	// Path is absolute and clean.
+	Path string
+	// Exists is false if the last component does not exist, e.g. for a
+	// file about to be created. Path then names the file in its resolved
+	// parent directory.
+	Exists bool
+}
+
+// resolveTraceePath resolves path the way the kernel would for the tracee
+// with the given pid. Absolute paths and absolute symlinks start at the
+// tracee's root (/proc/<pid>/root), which honours chroots and mount
+// namespaces. Relative paths start at dirfd of the tracee, or at its
+// working directory for AT_FDCWD. Symlinks are followed component by
+// component. The last component is only followed if followLast is set,
+// matching syscalls like unlink or O_NOFOLLOW that act on the link itself.
+func resolveTraceePath(pid int, dirfd int, path string, followLast bool) (resolvedPath, error) {
+	if path == "" {
+		return resolvedPath{}, unix.ENOENT
+	}
+
+	root, err := unix.Open(fmt.Sprintf("/proc/%d/root", pid), unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return resolvedPath{}, fmt.Errorf("unable to open root of pid %d: %w", pid, err)
+	}
+	defer unix.Close(root)
+
+	var start string
+	if strings.HasPrefix(path, "/") {
+		start = fmt.Sprintf("/proc/%d/root", pid)
+	} else if dirfd == unix.AT_FDCWD {
+		start = fmt.Sprintf("/proc/%d/cwd", pid)
+	} else {
+		start = fmt.Sprintf("/proc/%d/fd/%d", pid, dirfd)
+	}
+	cur, err := unix.Open(start, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return resolvedPath{}, fmt.Errorf("unable to open %s: %w", start, err)
+	}
+	defer func() { unix.Close(cur) }()
+
+	var rootStat unix.Stat_t
+	if err := unix.Fstat(root, &rootStat); err != nil {
+		return resolvedPath{}, err
+	}
+
+	components := strings.Split(path, "/")
+	symlinks := 0
+	for len(components) > 0 {
+		name := components[0]
+		components = components[1:]
+		last := len(components) == 0
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			// The tracee cannot leave its root with "..".
+			var st unix.Stat_t
+			if err := unix.Fstat(cur, &st); err != nil {
+				return resolvedPath{}, err
+			}
+			if st.Dev == rootStat.Dev && st.Ino == rootStat.Ino {
+				continue
+			}
+		}
+
+		next, err := unix.Openat(cur, name, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err == unix.ENOENT || err == unix.ENOTDIR {
+			// The kernel fails on this component, so nothing beyond it
+			// can be reached. The rest of the path is joined lexically.
+			dir, err := fdPath(cur)
+			if err != nil {
+				return resolvedPath{}, err
+			}
+			rest := filepath.Join(append([]string{dir, name}, components...)...)
+			return resolvedPath{Path: filepath.Clean(rest)}, nil
+		} else if err != nil {
+			return resolvedPath{}, err
+		}
+
+		var st unix.Stat_t
+		if err := unix.Fstat(next, &st); err != nil {
+			unix.Close(next)
+			return resolvedPath{}, err
+		}
+		if st.Mode&unix.S_IFMT != unix.S_IFLNK || (last && !followLast) {
+			unix.Close(cur)
+			cur = next
+			continue
+		}
+
+		symlinks++
+		if symlinks > maxSymlinks {
+			unix.Close(next)
+			return resolvedPath{}, unix.ELOOP
+		}
+		target, err := readlinkFd(next)
+		unix.Close(next)
+		if err != nil {
+			return resolvedPath{}, err
+		}
+		if name == "self" || name == "thread-self" {
+			// The magic links of procfs point to the process reading
+			// them, which is the gatekeeper and not the tracee.
+			if t, ok := procSelfTarget(cur, pid, name); ok {
+				target = t
+			}
+		}
+		if strings.HasPrefix(target, "/") {
+			dup, err := unix.Openat(root, ".", unix.O_PATH|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return resolvedPath{}, err
+			}
+			unix.Close(cur)
+			cur = dup
+		}
+		components = append(strings.Split(target, "/"), components...)
+	}
+
+	resolved, err := fdPath(cur)
+	if err != nil {
+		return resolvedPath{}, err
+	}
+	return resolvedPath{Path: resolved, Exists: true}, nil
+}
+
+// procSelfTarget returns the target /proc/self or /proc/thread-self has for
+// the tracee with the given pid, if dir is the root of a procfs.
+func procSelfTarget(dir int, pid int, name string) (string, bool) {
+	var fs unix.Statfs_t
+	if err := unix.Fstatfs(dir, &fs); err != nil || fs.Type != unix.PROC_SUPER_MAGIC {
+		return "", false
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(dir, &st); err != nil || st.Ino != procRootIno {
+		return "", false
+	}
+	tgid := traceeTgid(pid)
+	if name == "self" {
+		return strconv.Itoa(tgid), true
+	}
+	return fmt.Sprintf("%d/task/%d", tgid, pid), true
+}
+
+// traceeTgid returns the thread group id of the tracee thread pid, which is
+// the pid of its process.
+func traceeTgid(pid int) int {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return pid
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if v, ok := strings.CutPrefix(line, "Tgid:"); ok {
+			if tgid, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return tgid
+			}
+		}
+	}
+	return pid
+}
+
+// fdPath returns the path of the file referred to by fd of the gatekeeper.
+func fdPath(fd int) (string, error) {
+	p, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(p), nil
+}
+
+// readlinkFd reads the target of the symlink referred to by the O_PATH fd.
+func readlinkFd(fd int) (string, error) {
+	buf := make([]byte, unix.PathMax)
+	n, err := unix.Readlinkat(fd, "", buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
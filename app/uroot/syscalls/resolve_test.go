@@ -0,0 +1,162 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"golang.org/x/sys/unix"
+)
+
+func TestResolveTraceePath(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// td/dir/file, td/abs -> outside, td/rel -> dir/file, td/dirlink -> dir,
+	// td/dangling -> outside/new, td/loop -> loop
+	if err := os.MkdirAll(filepath.Join(td, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(td, "dir", "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"abs":      outside,
+		"rel":      "dir/file",
+		"dirlink":  "dir",
+		"dangling": filepath.Join(outside, "new"),
+		"loop":     "loop",
+	} {
+		if err := os.Symlink(target, filepath.Join(td, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		followLast bool
+		want       resolvedPath
+		wantErr    error
+	}{
+		{"plain file", filepath.Join(td, "dir", "file"), true, resolvedPath{filepath.Join(td, "dir", "file"), true}, nil},
+		{"absolute symlink", filepath.Join(td, "abs"), true, resolvedPath{outside, true}, nil},
+		{"absolute symlink in middle", filepath.Join(td, "abs") + "/x", true, resolvedPath{filepath.Join(outside, "x"), false}, nil},
+		{"relative symlink", filepath.Join(td, "rel"), true, resolvedPath{filepath.Join(td, "dir", "file"), true}, nil},
+		{"symlink then dotdot", td + "/dirlink/../rel", true, resolvedPath{filepath.Join(td, "dir", "file"), true}, nil},
+		{"last symlink not followed", filepath.Join(td, "abs"), false, resolvedPath{filepath.Join(td, "abs"), true}, nil},
+		{"trailing slash follows symlink", filepath.Join(td, "abs") + "/", false, resolvedPath{outside, true}, nil},
+		{"dangling symlink", filepath.Join(td, "dangling"), true, resolvedPath{filepath.Join(outside, "new"), false}, nil},
+		{"missing file", filepath.Join(td, "dir", "new"), true, resolvedPath{filepath.Join(td, "dir", "new"), false}, nil},
+		{"missing directory", td + "/missing/../../x", true, resolvedPath{filepath.Join(filepath.Dir(td), "x"), false}, nil},
+		{"dotdot above root", "/../../" + td[1:], true, resolvedPath{td, true}, nil},
+		{"symlink loop", filepath.Join(td, "loop"), true, resolvedPath{}, unix.ELOOP},
+		{"empty path", "", true, resolvedPath{}, unix.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTraceePath(os.Getpid(), unix.AT_FDCWD, tt.path, tt.followLast)
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveTraceePathRelativeToDirfd(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := resolveTraceePath(os.Getpid(), int(f.Fd()), "a/../b", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := resolvedPath{filepath.Join(td, "b"), false}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestResolveTraceePathProcSelf(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A tracee with a different working directory than the gatekeeper
+	cmd := exec.Command("sleep", "10")
+	cmd.Dir = td
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+
+	for _, path := range []string{"/proc/self/cwd/x", "/proc/thread-self/cwd/x", "/proc/./self/cwd/x"} {
+		got, err := resolveTraceePath(pid, unix.AT_FDCWD, path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := resolvedPath{filepath.Join(td, "x"), false}
+		if got != want {
+			t.Fatalf("%s: expected %+v, got %+v", path, want, got)
+		}
+	}
+
+	got, err := resolveTraceePath(pid, unix.AT_FDCWD, "/proc/self", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (resolvedPath{"/proc/self", true}); got != want {
+		t.Fatalf("expected the link itself %+v, got %+v", want, got)
+	}
+}
+
+func TestPathIsNotAllowedThroughSymlink(t *testing.T) {
+	td := t.TempDir()
+	outside := t.TempDir()
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+
+	path := filepath.Join(td, "link")
+	if err := os.Symlink(outside, path); err != nil {
+		t.Fatal(err)
+	}
+
+	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
+	base := uintptr(0x5000)
+	s.Args[0] = SyscallArgument{Value: base}
+	s.Reader = makeReaderFor(path, base)
+
+	if PathIsAllowed(s, 0, -1) {
+		t.Fatalf("expected symlink %s to %s NOT to be allowed", path, outside)
+	}
+	if !PathIsAllowedNoFollow(s, 0, -1) {
+		t.Fatalf("expected symlink %s itself to be allowed", path)
+	}
+}
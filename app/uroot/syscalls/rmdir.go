@@ -2,19 +2,15 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
-
 // IsRmdirAllowed checks rmdir(pathname).
 // pathname is arg 0.
 func IsRmdirAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
-	if len(runtime.Get().FileSystemAllowedPaths) > 0 {
-		return PathIsAllowed(s, 0, -1)
+	if len(s.config().FileSystemAllowedPaths) > 0 {
+		return PathIsAllowedNoFollow(s, 0, -1)
 	}
 	return true
 }
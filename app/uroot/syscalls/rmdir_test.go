@@ -3,6 +3,7 @@
 package syscalls
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -11,11 +12,14 @@ import (
 
 func TestRmdirAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(td, "dir")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5100)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -28,11 +32,14 @@ func TestRmdirAllowed(t *testing.T) {
 func TestRmdirNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(other, "dir")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5101)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
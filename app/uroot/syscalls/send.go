@@ -0,0 +1,88 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// isSendDestinationAllowed checks the destination sockaddr of a message.
+// Connectionless sockets send to it without a connect, so it is checked like
+// the address of a connect. Without a destination, the message goes to the
+// connected peer, which was checked by connect.
+func isSendDestinationAllowed(s Syscall, addr Addr, length uint32) bool {
+	if addr == 0 || length == 0 {
+		return true
+	}
+	family, ok := readSockaddrFamily(s, addr)
+	if !ok {
+		return false
+	}
+	if family == uint16(unix.AF_UNSPEC) {
+		// UDP sends to the address of an AF_UNSPEC sockaddr as if it was
+		// AF_INET.
+		family = uint16(unix.AF_INET)
+	}
+	if !isDestinationFamilyAllowed(s, family) {
+		fmt.Printf("sending to a destination of family %d is not allowed\n", family)
+		return false
+	}
+	if family == uint16(unix.AF_INET) || family == uint16(unix.AF_INET6) {
+		dest, _ := ReadInetSockaddr(s.Reader, addr, length, true)
+		return isInetDestinationAllowed(s, dest) && isLoopbackBound(s, s.Args[0].Int(), dest)
+	}
+	return true
+}
+
+// isMsgDestinationAllowed checks the msg_name destinations of the messages
+// of sendmsg(sockfd, msg, flags) or sendmmsg(sockfd, msgvec, vlen, flags),
+// and the data sent with them.
+func isMsgDestinationAllowed(s Syscall, mmsg bool) bool {
+	if s.Reader == nil {
+		return false
+	}
+	msgs, err := ReadMsghdrs(s.Reader, s.Args[1].Pointer(), int(s.Args[2].Uint()), mmsg)
+	if err != nil {
+		fmt.Printf("unable to read messages of pid %d: %s\n", s.TraceePID, err.Error())
+		return false
+	}
+	fd := s.Args[0].Int()
+	for i, hdr := range msgs {
+		if !isSendDestinationAllowed(s, Addr(hdr.Name), hdr.Namelen) {
+			return false
+		}
+		dest, _ := ReadInetSockaddr(s.Reader, Addr(hdr.Name), hdr.Namelen, true)
+		data := iovecData(s, Addr(hdr.Iov), hdr.Iovlen)
+		if !isDNSQueryAllowed(s, fd, dest, data) {
+			return false
+		}
+		// Only the first message can be the first data on a connection
+		if i == 0 && !isFirstWriteHostAllowed(s, fd, data) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSendtoAllowed checks sendto(sockfd, buf, len, flags, dest_addr, addrlen)
+// like write, its destination like connect, and the data in buf.
+func IsSendtoAllowed(s Syscall, isEnter bool) bool {
+	if !IsWriteAllowed(s, isEnter) || !isFastOpenAllowed(s, s.Args[3].Uint()) || !isSendDestinationAllowed(s, s.Args[4].Pointer(), s.Args[5].Uint()) {
+		return false
+	}
+	dest, _ := ReadInetSockaddr(s.Reader, s.Args[4].Pointer(), s.Args[5].Uint(), true)
+	return isSentDataAllowed(s, s.Args[0].Int(), dest, bufferData(s, s.Args[1].Pointer(), s.Args[2].Uint64()))
+}
+
+// IsSendmsgAllowed checks sendmsg(sockfd, msg, flags) like write, its
+// destination like connect, and the fds passed with it.
+func IsSendmsgAllowed(s Syscall, isEnter bool) bool {
+	return IsWriteAllowed(s, isEnter) && isFastOpenAllowed(s, s.Args[2].Uint()) && isMsgDestinationAllowed(s, false) && isSendRightsAllowed(s, false)
+}
+
+// IsSendmmsgAllowed checks sendmmsg(sockfd, msgvec, vlen, flags).
+func IsSendmmsgAllowed(s Syscall, isEnter bool) bool {
+	return IsWriteAllowed(s, isEnter) && isFastOpenAllowed(s, s.Args[3].Uint()) && isMsgDestinationAllowed(s, true) && isSendRightsAllowed(s, true)
+}
@@ -0,0 +1,166 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+	"golang.org/x/sys/unix"
+)
+
+func TestSendDestinationAllowed(t *testing.T) {
+	const (
+		sock     = 1000
+		hdrAddr  = 0x1000
+		nameAddr = 0x2000
+	)
+	fds := args.NewFdTable()
+	fds.Set(sock, args.FdInfo{Type: args.FDSocket})
+
+	inet := encode(t, unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: [4]byte{192, 0, 2, 1}})
+	unspec := encode(t, unix.RawSockaddrInet4{Family: unix.AF_UNSPEC, Addr: [4]byte{192, 0, 2, 1}})
+	local := encode(t, unix.RawSockaddrUnix{Family: unix.AF_UNIX})
+
+	sendto := func(name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Reader = fakeMemory{nameAddr: name}.read
+			if name != nil {
+				s.Args[4] = SyscallArgument{Value: nameAddr}
+				s.Args[5] = SyscallArgument{Value: uintptr(len(name))}
+			}
+			return IsSendtoAllowed(*s, true)
+		}
+	}
+	sendmsg := func(name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			hdr := Msghdr{}
+			if name != nil {
+				hdr.Name, hdr.Namelen = nameAddr, uint32(len(name))
+			}
+			s.Reader = fakeMemory{hdrAddr: encode(t, hdr), nameAddr: name}.read
+			s.Args[1] = SyscallArgument{Value: hdrAddr}
+			return IsSendmsgAllowed(*s, true)
+		}
+	}
+
+	// sendmmsg sends a message to the connected peer and one to name, in a
+	// msgvec of maxMmsgs entries that ends before an unmapped page.
+	sendmmsg := func(name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			hdrs := []Mmsghdr{{}, {Hdr: Msghdr{Name: nameAddr, Namelen: uint32(len(name))}}}
+			s.Reader = fakeMemory{hdrAddr: encode(t, hdrs), nameAddr: name}.read
+			s.Args[1] = SyscallArgument{Value: hdrAddr}
+			s.Args[2] = SyscallArgument{Value: maxMmsgs}
+			return IsSendmmsgAllowed(*s, true)
+		}
+	}
+	unmapped := func(s *Syscall) bool {
+		s.Reader = fakeMemory{}.read
+		s.Args[1] = SyscallArgument{Value: hdrAddr}
+		s.Args[2] = SyscallArgument{Value: 1}
+		return IsSendmmsgAllowed(*s, true)
+	}
+
+	localOnly := runtime.NetworkConfig{LocalSocketsAllow: true}
+	client := runtime.NetworkConfig{NetworkAllowClient: true}
+	tests := []struct {
+		name    string
+		check   func(s *Syscall) bool
+		network runtime.NetworkConfig
+		want    bool
+	}{
+		{"sendto connected", sendto(nil), localOnly, true},
+		{"sendto inet without client", sendto(inet), localOnly, false},
+		{"sendto inet with client", sendto(inet), client, true},
+		{"sendto unspec without client", sendto(unspec), localOnly, false},
+		{"sendto unix", sendto(local), localOnly, true},
+		{"sendto unix without local sockets", sendto(local), client, false},
+		{"sendmsg connected", sendmsg(nil), localOnly, true},
+		{"sendmsg inet without client", sendmsg(inet), localOnly, false},
+		{"sendmsg inet with client", sendmsg(inet), client, true},
+		{"sendmmsg inet before unmapped page without client", sendmmsg(inet), localOnly, false},
+		{"sendmmsg inet before unmapped page with client", sendmmsg(inet), client, true},
+		{"sendmmsg unmapped msgvec", unmapped, client, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Config:    &runtime.Config{NetworkConfig: tt.network},
+			}
+			s.Args[0] = SyscallArgument{Value: sock}
+
+			if got := tt.check(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEgressProxyDestination(t *testing.T) {
+	const nameAddr = 0x2000
+	inet := func(addr [4]byte, port uint16) []byte {
+		b := encode(t, unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: addr})
+		binary.BigEndian.PutUint16(b[2:4], port)
+		return b
+	}
+	mapped := encode(t, unix.RawSockaddrInet6{Family: unix.AF_INET6, Addr: [16]byte{10: 0xff, 11: 0xff, 12: 127, 15: 1}})
+	binary.BigEndian.PutUint16(mapped[2:4], 3128)
+
+	connect := func(name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[1] = SyscallArgument{Value: nameAddr}
+			s.Args[2] = SyscallArgument{Value: uintptr(len(name))}
+			return IsConnectAllowed(*s, true)
+		}
+	}
+	sendto := func(name []byte) func(s *Syscall) bool {
+		return func(s *Syscall) bool {
+			s.Args[4] = SyscallArgument{Value: nameAddr}
+			s.Args[5] = SyscallArgument{Value: uintptr(len(name))}
+			return IsSendtoAllowed(*s, true)
+		}
+	}
+
+	proxy := inet([4]byte{127, 0, 0, 1}, 3128)
+	other := inet([4]byte{192, 0, 2, 1}, 443)
+	tests := []struct {
+		name  string
+		addr  []byte
+		check func(name []byte) func(s *Syscall) bool
+		want  bool
+	}{
+		{"connect to proxy", proxy, connect, true},
+		{"connect to proxy as mapped address", mapped, connect, true},
+		{"connect to other port", inet([4]byte{127, 0, 0, 1}, 3129), connect, false},
+		{"connect to other host", other, connect, false},
+		{"sendto other host", other, sendto, false},
+	}
+
+	fds := args.NewFdTable()
+	fds.Set(1000, args.FdInfo{Type: args.FDSocket, SockType: unix.SOCK_DGRAM})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       fds,
+				Reader:    fakeMemory{nameAddr: tt.addr}.read,
+				Config: &runtime.Config{NetworkConfig: runtime.NetworkConfig{
+					NetworkAllowClient: true,
+					NetworkEgressProxy: "127.0.0.1:3128",
+				}},
+			}
+			s.Args[0] = SyscallArgument{Value: 1000}
+
+			if got := tt.check(tt.addr)(&s); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@
 package syscalls
 
 import (
-	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot/syscalls/args"
 )
 
@@ -11,9 +10,9 @@ import (
 // Unified signature: derive fd traits via args helpers using Syscall.TraceePID.
 func IsShutdownAllowed(s Syscall, isEnter bool) bool {
 	fd := s.Args[0].Int()
-	isSocket := args.IsSocket(s.TraceePID, fd)
-	if (runtime.Get().NetworkAllowServer || runtime.Get().NetworkAllowClient || runtime.Get().LocalSocketsAllow) && isSocket {
+	isSocket := s.FdType(fd) == args.FDSocket
+	if (s.config().NetworkAllowServer || s.config().NetworkAllowClient || s.config().NetworkAllowLoopback || s.config().LocalSocketsAllow) && isSocket {
 		return true
 	}
-	return args.IsStandardStream(fd)
+	return s.IsStandardStream(fd)
 }
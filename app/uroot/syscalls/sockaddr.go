@@ -0,0 +1,90 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"golang.org/x/sys/unix"
+)
+
+// ReadInetSockaddr reads the AF_INET or AF_INET6 sockaddr of length bytes at
+// addr from the tracee. If unspecAsInet is set, AF_UNSPEC is read like
+// AF_INET, as UDP sends to the address of such a destination.
+func ReadInetSockaddr(read func(addr Addr, v interface{}) (int, error), addr Addr, length uint32, unspecAsInet bool) (netip.AddrPort, bool) {
+	if addr == 0 || read == nil || length < 2 {
+		return netip.AddrPort{}, false
+	}
+	raw := make([]byte, min(length, unix.SizeofSockaddrInet6))
+	if _, err := read(addr, raw); err != nil {
+		return netip.AddrPort{}, false
+	}
+	if unspecAsInet && binary.NativeEndian.Uint16(raw) == unix.AF_UNSPEC {
+		binary.NativeEndian.PutUint16(raw, unix.AF_INET)
+	}
+	return parseInetSockaddr(raw)
+}
+
+// parseInetSockaddr decodes a raw sockaddr_in or sockaddr_in6. The port is in
+// network byte order.
+func parseInetSockaddr(raw []byte) (netip.AddrPort, bool) {
+	switch binary.NativeEndian.Uint16(raw) {
+	case unix.AF_INET:
+		if len(raw) < unix.SizeofSockaddrInet4 {
+			return netip.AddrPort{}, false
+		}
+		ip := netip.AddrFrom4([4]byte(raw[4:8]))
+		return netip.AddrPortFrom(ip, binary.BigEndian.Uint16(raw[2:4])), true
+	case unix.AF_INET6:
+		if len(raw) < unix.SizeofSockaddrInet6 {
+			return netip.AddrPort{}, false
+		}
+		ip := netip.AddrFrom16([16]byte(raw[8:24]))
+		return netip.AddrPortFrom(ip, binary.BigEndian.Uint16(raw[2:4])), true
+	}
+	return netip.AddrPort{}, false
+}
+
+// socketPeer returns the address the AF_INET or AF_INET6 socket fd of the
+// tracee is connected to. The shadow fd table is preferred over looking up
+// the socket with pidfd_getfd.
+func socketPeer(s Syscall, fd int32) (netip.AddrPort, bool) {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok && info.Peer.IsValid() {
+			return info.Peer, true
+		}
+	}
+	sa, err := traceeSockaddr(s, fd, unix.Getpeername)
+	if err != nil {
+		return netip.AddrPort{}, false
+	}
+	return inetAddrPort(sa)
+}
+
+// traceeSockaddr looks up an address of the socket fd of the tracee with
+// getName, e.g. unix.Getsockname, on a duplicate from pidfd_getfd.
+func traceeSockaddr(s Syscall, fd int32, getName func(fd int) (unix.Sockaddr, error)) (unix.Sockaddr, error) {
+	pidfd, err := unix.PidfdOpen(s.TraceePID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(pidfd)
+	sock, err := unix.PidfdGetfd(pidfd, int(fd), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(sock)
+	return getName(sock)
+}
+
+// inetAddrPort converts an AF_INET or AF_INET6 sockaddr.
+func inetAddrPort(sa unix.Sockaddr) (netip.AddrPort, bool) {
+	switch addr := sa.(type) {
+	case *unix.SockaddrInet4:
+		return netip.AddrPortFrom(netip.AddrFrom4(addr.Addr), uint16(addr.Port)), true
+	case *unix.SockaddrInet6:
+		return netip.AddrPortFrom(netip.AddrFrom16(addr.Addr), uint16(addr.Port)), true
+	}
+	return netip.AddrPort{}, false
+}
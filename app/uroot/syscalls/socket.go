@@ -4,26 +4,93 @@ package syscalls
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
-	"github.com/cuandari/lib/app/runtime"
 	"golang.org/x/sys/unix"
 )
 
-// IsSocketAllowed determines allowance based on socket domain and runtime flags.
-// Signature unified to accept Syscall; extracts domain from args.
+// netlinkProtocols maps the names of netlink protocols to their numbers.
+var netlinkProtocols = map[string]int{
+	"route":          unix.NETLINK_ROUTE,
+	"usersock":       unix.NETLINK_USERSOCK,
+	"firewall":       unix.NETLINK_FIREWALL,
+	"sock_diag":      unix.NETLINK_SOCK_DIAG,
+	"nflog":          unix.NETLINK_NFLOG,
+	"xfrm":           unix.NETLINK_XFRM,
+	"selinux":        unix.NETLINK_SELINUX,
+	"iscsi":          unix.NETLINK_ISCSI,
+	"audit":          unix.NETLINK_AUDIT,
+	"fib_lookup":     unix.NETLINK_FIB_LOOKUP,
+	"connector":      unix.NETLINK_CONNECTOR,
+	"netfilter":      unix.NETLINK_NETFILTER,
+	"ip6_fw":         unix.NETLINK_IP6_FW,
+	"dnrtmsg":        unix.NETLINK_DNRTMSG,
+	"kobject_uevent": unix.NETLINK_KOBJECT_UEVENT,
+	"generic":        unix.NETLINK_GENERIC,
+	"scsitransport":  unix.NETLINK_SCSITRANSPORT,
+	"ecryptfs":       unix.NETLINK_ECRYPTFS,
+	"rdma":           unix.NETLINK_RDMA,
+	"crypto":         unix.NETLINK_CRYPTO,
+	"smc":            unix.NETLINK_SMC,
+}
+
+// NetlinkProtocol returns the number of the netlink protocol name, e.g.
+// "route" or "NETLINK_ROUTE".
+func NetlinkProtocol(name string) (int, bool) {
+	p, ok := netlinkProtocols[strings.TrimPrefix(strings.ToLower(name), "netlink_")]
+	return p, ok
+}
+
+// isNetlinkProtocolAllowed returns true if netlink sockets of protocol may be
+// created. NETLINK_ROUTE is always allowed, as getaddrinfo and interface
+// enumeration need it. Other protocols, e.g. NETLINK_AUDIT or
+// NETLINK_KOBJECT_UEVENT, reach kernel subsystems and must be allowlisted.
+func isNetlinkProtocolAllowed(s Syscall, protocol int) bool {
+	if protocol == unix.NETLINK_ROUTE {
+		return true
+	}
+	return slices.ContainsFunc(s.config().LocalSocketsNetlinkProtocols, func(name string) bool {
+		p, ok := NetlinkProtocol(name)
+		return ok && p == protocol
+	})
+}
+
+// IsSocketAllowed determines allowance based on socket domain, type,
+// protocol and runtime flags. Signature unified to accept Syscall; extracts
+// socket(domain, type, protocol) from args.
 func IsSocketAllowed(s Syscall, isEnter bool) bool {
 	domain := int(s.Args[0].Int())
+	typ := int(s.Args[1].Int()) &^ (unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC)
+	protocol := int(s.Args[2].Int())
 
 	// Local-only families
-	if domain == unix.AF_UNIX || domain == unix.AF_NETLINK {
-		fmt.Println("socket domain:", domain, "allowed as local socket", runtime.Get().LocalSocketsAllow)
-		return runtime.Get().LocalSocketsAllow
+	if domain == unix.AF_UNIX {
+		fmt.Println("socket domain:", domain, "allowed as local socket", s.config().LocalSocketsAllow)
+		return s.config().LocalSocketsAllow
+	}
+	if domain == unix.AF_NETLINK {
+		allowed := s.config().LocalSocketsAllow && isNetlinkProtocolAllowed(s, protocol)
+		fmt.Println("socket domain:", domain, "protocol:", protocol, "allowed as local socket", allowed)
+		return allowed
 	}
 
 	// Network families
-	if domain == unix.AF_INET || domain == unix.AF_INET6 || domain == unix.AF_PACKET {
-		fmt.Println("socket domain:", domain, "allowed as network socket", runtime.Get().NetworkAllowClient || runtime.Get().NetworkAllowServer)
-		return runtime.Get().NetworkAllowClient || runtime.Get().NetworkAllowServer
+	network := s.config().NetworkAllowClient || s.config().NetworkAllowServer
+	inet := domain == unix.AF_INET || domain == unix.AF_INET6
+	if domain == unix.AF_PACKET || (inet && (typ == unix.SOCK_RAW || typ == unix.SOCK_PACKET)) {
+		// Raw sockets can forge and sniff packets of other connections.
+		// The kernel turns the obsolete SOCK_PACKET on AF_INET into a
+		// packet socket.
+		allowed := network && s.config().NetworkAllowRawSockets
+		fmt.Println("socket domain:", domain, "type:", typ, "allowed as raw socket", allowed)
+		return allowed
+	}
+	if inet {
+		// Loopback-only sockets are restricted by their addresses.
+		allowed := network || s.config().NetworkAllowLoopback
+		fmt.Println("socket domain:", domain, "allowed as network socket", allowed)
+		return allowed
 	}
 
 	fmt.Println("socket domain:", domain, "not explicitly allowed")
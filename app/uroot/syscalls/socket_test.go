@@ -0,0 +1,69 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"golang.org/x/sys/unix"
+)
+
+func TestIsSocketAllowed(t *testing.T) {
+	client := runtime.NetworkConfig{NetworkAllowClient: true}
+	raw := runtime.NetworkConfig{NetworkAllowClient: true, NetworkAllowRawSockets: true}
+	local := runtime.NetworkConfig{LocalSocketsAllow: true}
+	audit := runtime.NetworkConfig{LocalSocketsAllow: true, LocalSocketsNetlinkProtocols: []string{"NETLINK_AUDIT"}}
+
+	tests := []struct {
+		name     string
+		domain   int
+		typ      int
+		protocol int
+		network  runtime.NetworkConfig
+		want     bool
+	}{
+		{"tcp client", unix.AF_INET, unix.SOCK_STREAM | unix.SOCK_CLOEXEC, 0, client, true},
+		{"udp6 client", unix.AF_INET6, unix.SOCK_DGRAM, 0, client, true},
+		{"tcp without network", unix.AF_INET, unix.SOCK_STREAM, 0, local, false},
+		{"icmp ping socket", unix.AF_INET, unix.SOCK_DGRAM, unix.IPPROTO_ICMP, client, true},
+		{"raw ip socket", unix.AF_INET, unix.SOCK_RAW | unix.SOCK_NONBLOCK, unix.IPPROTO_ICMP, client, false},
+		{"raw ip socket allowed", unix.AF_INET6, unix.SOCK_RAW, unix.IPPROTO_ICMPV6, raw, true},
+		{"packet socket", unix.AF_PACKET, unix.SOCK_DGRAM, 0, client, false},
+		{"inet packet socket", unix.AF_INET, unix.SOCK_PACKET, 0, client, false},
+		{"inet packet socket allowed", unix.AF_INET, unix.SOCK_PACKET, 0, raw, true},
+		{"packet socket allowed", unix.AF_PACKET, unix.SOCK_RAW, 0, raw, true},
+		{"raw sockets without network", unix.AF_PACKET, unix.SOCK_RAW, 0, runtime.NetworkConfig{NetworkAllowRawSockets: true}, false},
+		{"unix socket", unix.AF_UNIX, unix.SOCK_STREAM, 0, local, true},
+		{"netlink route", unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE, local, true},
+		{"netlink route without local sockets", unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_ROUTE, client, false},
+		{"netlink audit", unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_AUDIT, local, false},
+		{"netlink audit allowed", unix.AF_NETLINK, unix.SOCK_RAW, unix.NETLINK_AUDIT, audit, true},
+		{"netlink uevent", unix.AF_NETLINK, unix.SOCK_DGRAM, unix.NETLINK_KOBJECT_UEVENT, audit, false},
+		{"vsock", unix.AF_VSOCK, unix.SOCK_STREAM, 0, raw, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{Config: &runtime.Config{NetworkConfig: tt.network}}
+			s.Args[0] = SyscallArgument{Value: uintptr(tt.domain)}
+			s.Args[1] = SyscallArgument{Value: uintptr(tt.typ)}
+			s.Args[2] = SyscallArgument{Value: uintptr(tt.protocol)}
+
+			if got := IsSocketAllowed(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNetlinkProtocol(t *testing.T) {
+	for _, name := range []string{"route", "NETLINK_ROUTE", "Netlink_Route"} {
+		if p, ok := NetlinkProtocol(name); !ok || p != unix.NETLINK_ROUTE {
+			t.Fatalf("expected %s to be NETLINK_ROUTE, got %d %v", name, p, ok)
+		}
+	}
+	if _, ok := NetlinkProtocol("bogus"); ok {
+		t.Fatalf("expected unknown protocol to be rejected")
+	}
+}
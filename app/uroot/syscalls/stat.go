@@ -0,0 +1,28 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsStatAllowed checks stat(pathname, statbuf).
+// pathname is arg 0.
+func IsStatAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowed(s, 0, -1)
+}
+
+// IsLstatAllowed checks lstat(pathname, statbuf), which does not follow a
+// symlink in the last component.
+// pathname is arg 0.
+func IsLstatAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 0, -1)
+}
+
+// IsNewfstatatAllowed checks newfstatat(dirfd, pathname, statbuf, flags).
+// pathname is arg 1, dirfd is arg 0.
+func IsNewfstatatAllowed(s Syscall, isEnter bool) bool {
+	return PathAtIsAllowed(s, 1, 0, int(s.Args[3].Int()))
+}
+
+// IsStatxAllowed checks statx(dirfd, pathname, flags, mask, statxbuf).
+// pathname is arg 1, dirfd is arg 0.
+func IsStatxAllowed(s Syscall, isEnter bool) bool {
+	return PathAtIsAllowed(s, 1, 0, int(s.Args[2].Int()))
+}
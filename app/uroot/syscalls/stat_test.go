@@ -0,0 +1,53 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsStatAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsStatAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{0: filepath.Join(allowed, "file")}, want: true},
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+		{name: "symlink to outside", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: false},
+	})
+}
+
+func TestIsLstatAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsLstatAllowed, []pathSyscallTest{
+		{name: "outside", paths: map[int]string{0: filepath.Join(outside, "file")}, want: false},
+		{name: "symlink itself", paths: map[int]string{0: filepath.Join(allowed, "link")}, want: true},
+	})
+}
+
+func TestIsNewfstatatAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsNewfstatatAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink to outside", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 3: unix.AT_SYMLINK_NOFOLLOW}, want: true},
+		{name: "empty path of fd", paths: map[int]string{1: ""}, ints: map[int]uintptr{0: 3, 3: unix.AT_EMPTY_PATH}, want: true},
+		{name: "empty path without flag", paths: map[int]string{1: ""}, ints: map[int]uintptr{0: 3}, want: false},
+	})
+}
+
+func TestIsStatxAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsStatxAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 2: unix.AT_SYMLINK_NOFOLLOW}, want: true},
+		{name: "empty path of fd", paths: map[int]string{1: ""}, ints: map[int]uintptr{0: 3, 2: unix.AT_EMPTY_PATH}, want: true},
+	})
+}
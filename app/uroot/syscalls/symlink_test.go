@@ -3,6 +3,7 @@
 package syscalls
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -11,11 +12,14 @@ import (
 
 func TestSymlinkAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	linkpath := filepath.Join(td, "lnk")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	b1 := uintptr(0x7020)
 	b2 := uintptr(0x8028)
 	s.Args[0] = SyscallArgument{Value: b1} // target
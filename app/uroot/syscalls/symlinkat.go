@@ -2,19 +2,15 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
-
 // IsSymlinkAtAllowed checks symlinkat(target, newdirfd, linkpath).
 // linkpath is arg 2, newdirfd is arg 1.
 func IsSymlinkAtAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
-	if len(runtime.Get().FileSystemAllowedPaths) > 0 {
-		return PathIsAllowed(s, 2, 1)
+	if len(s.config().FileSystemAllowedPaths) > 0 {
+		return PathIsAllowedNoFollow(s, 2, 1)
 	}
 	return true
 }
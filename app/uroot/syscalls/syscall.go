@@ -1,5 +1,14 @@
 package syscalls
 
+import (
+	"slices"
+	"time"
+
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
 // Syscall bundles arguments and minimal tracee context so helpers share one signature.
 type Syscall struct {
 	// Args are the arguments to the syscall.
@@ -11,4 +20,58 @@ type Syscall struct {
 	// Reader allows helpers to read tracee memory (e.g., sockaddr, open_how).
 	// Provide as a function to avoid cross-package type coupling.
 	Reader func(addr Addr, v interface{}) (int, error)
+
+	// Fds is the shadow fd table of the tracee. If nil, or if an fd is not
+	// recorded, fds are looked up in /proc.
+	Fds *args.FdTable
+
+	// Stdio identifies the standard streams the tracee was started with.
+	Stdio []args.FileID
+
+	// SessionAge is the time since the gatekeeper session started.
+	SessionAge time.Duration
+
+	// Config is the policy the syscall is checked against. If nil, nothing
+	// is allowed.
+	Config *runtime.Config
+
+	// Audit records the network endpoints the tracee touches. If nil,
+	// nothing is recorded.
+	Audit *audit.Log
+}
+
+// config returns the policy the syscall is checked against. Without a
+// policy, an empty one is returned, which allows nothing.
+func (s Syscall) config() *runtime.Config {
+	if s.Config != nil {
+		return s.Config
+	}
+	return &runtime.Config{}
+}
+
+// FdType returns the type of fd of the tracee, one of the args.FD*
+// constants. The shadow fd table is preferred over /proc.
+func (s Syscall) FdType(fd int32) string {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok {
+			return info.Type
+		}
+	}
+	return args.FdType(s.TraceePID, fd)
+}
+
+// IsStandardStream returns true if fd refers to one of the standard streams
+// the tracee was started with, e.g. the pipes created by Exec or an inherited
+// terminal. A file or socket dup2'd onto fd 1 is not a standard stream.
+func (s Syscall) IsStandardStream(fd int32) bool {
+	if s.Fds != nil {
+		if info, ok := s.Fds.Get(fd); ok {
+			return info.Stdio
+		}
+	}
+	id, err := args.FdFileID(s.TraceePID, fd)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(s.Stdio, id)
 }
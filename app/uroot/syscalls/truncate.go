@@ -0,0 +1,9 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsTruncateAllowed checks truncate(path, length).
+// path is arg 0.
+func IsTruncateAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowed(s, 0, -1)
+}
@@ -3,6 +3,7 @@
 package syscalls
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -11,11 +12,14 @@ import (
 
 func TestUnlinkAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(td, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5000)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -28,11 +32,14 @@ func TestUnlinkAllowed(t *testing.T) {
 func TestUnlinkNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(other, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5001)
 	s.Args[0] = SyscallArgument{Value: base}
 	s.Reader = makeReaderFor(path, base)
@@ -2,19 +2,15 @@
 
 package syscalls
 
-import (
-	"github.com/cuandari/lib/app/runtime"
-)
-
 // IsUnlinkAtAllowed checks unlinkat(dirfd, pathname, flags).
 // pathname is arg 1, dirfd is arg 0.
 func IsUnlinkAtAllowed(s Syscall, isEnter bool) bool {
-	writeAllowed := runtime.Get().FileSystemAllowWrite
+	writeAllowed := s.config().FileSystemAllowWrite
 	if !writeAllowed {
 		return false
 	}
-	if len(runtime.Get().FileSystemAllowedPaths) > 0 {
-		return PathIsAllowed(s, 1, 0)
+	if len(s.config().FileSystemAllowedPaths) > 0 {
+		return PathIsAllowedNoFollow(s, 1, 0)
 	}
 	return true
 }
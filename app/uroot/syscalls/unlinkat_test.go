@@ -3,6 +3,7 @@
 package syscalls
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -11,11 +12,14 @@ import (
 
 func TestUnlinkAtAllowed(t *testing.T) {
 	td := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(td, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5200)
 	s.Args[1] = SyscallArgument{Value: base} // pathname
 	s.Args[0] = SyscallArgument{Value: uintptr(0)}
@@ -29,11 +33,14 @@ func TestUnlinkAtAllowed(t *testing.T) {
 func TestUnlinkAtNotAllowed(t *testing.T) {
 	td := t.TempDir()
 	other := t.TempDir()
-	runtime.Get().FileSystemAllowedPaths = []string{td}
-	runtime.Get().FileSystemAllowWrite = true
+	conf := &runtime.Config{}
+	conf.FileSystemAllowedPaths = []string{td}
+	conf.FileSystemAllowWrite = true
 
 	path := filepath.Join(other, "file.txt")
 	var s Syscall
+	s.Config = conf
+	s.TraceePID = os.Getpid()
 	base := uintptr(0x5201)
 	s.Args[1] = SyscallArgument{Value: base} // pathname
 	s.Args[0] = SyscallArgument{Value: uintptr(0)}
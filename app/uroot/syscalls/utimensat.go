@@ -0,0 +1,13 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsUtimensAtAllowed checks utimensat(dirfd, pathname, times, flags).
+// pathname is arg 1, dirfd is arg 0. A NULL pathname changes the times of
+// dirfd itself, which the tracee already holds open.
+func IsUtimensAtAllowed(s Syscall, isEnter bool) bool {
+	if s.Args[1].Pointer() == 0 {
+		return true
+	}
+	return PathAtIsAllowed(s, 1, 0, int(s.Args[3].Int()))
+}
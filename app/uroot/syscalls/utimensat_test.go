@@ -0,0 +1,22 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestIsUtimensAtAllowed(t *testing.T) {
+	allowed, outside, conf := pathFixture(t)
+
+	runPathSyscallTests(t, conf, IsUtimensAtAllowed, []pathSyscallTest{
+		{name: "allowed", paths: map[int]string{1: filepath.Join(allowed, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: true},
+		{name: "outside", paths: map[int]string{1: filepath.Join(outside, "file")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink to outside", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd}, want: false},
+		{name: "symlink nofollow", paths: map[int]string{1: filepath.Join(allowed, "link")}, ints: map[int]uintptr{0: atFdcwd, 3: unix.AT_SYMLINK_NOFOLLOW}, want: true},
+		{name: "NULL path of fd", ints: map[int]uintptr{0: 3}, want: true},
+	})
+}
@@ -4,35 +4,51 @@ package syscalls
 
 import (
 	"fmt"
+	"net/netip"
 
-	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot/syscalls/args"
 )
 
 // IsWriteAllowed decides allowance for write-like syscalls using fd context.
-// Unified signature: derive fd traits via args helpers using Syscall.TraceePID.
+// Unified signature: the fd type comes from the shadow fd table, or /proc.
 func IsWriteAllowed(s Syscall, isEnter bool) bool {
-	fd := s.Args[0].Int()
-	isStdStream := args.IsStandardStream(fd)
+	return isWriteFdAllowed(s, s.Args[0].Int())
+}
+
+// IsWriteBufferAllowed checks write(fd, buf, count) like IsWriteAllowed and,
+// on sockets, the data in buf.
+func IsWriteBufferAllowed(s Syscall, isEnter bool) bool {
+	return IsWriteAllowed(s, isEnter) && isSentDataAllowed(s, s.Args[0].Int(), netip.AddrPort{}, bufferData(s, s.Args[1].Pointer(), s.Args[2].Uint64()))
+}
+
+// IsWritevAllowed checks writev(fd, iov, iovcnt) like IsWriteAllowed and, on
+// sockets, the data in the iovecs.
+func IsWritevAllowed(s Syscall, isEnter bool) bool {
+	return IsWriteAllowed(s, isEnter) && isSentDataAllowed(s, s.Args[0].Int(), netip.AddrPort{}, iovecData(s, s.Args[1].Pointer(), s.Args[2].Uint64()))
+}
+
+// isSentDataAllowed inspects data sent on fd to dest, or to the peer of fd:
+// DNS queries, and the host the first data on a connection is for.
+func isSentDataAllowed(s Syscall, fd int32, dest netip.AddrPort, data sentData) bool {
+	return isDNSQueryAllowed(s, fd, dest, data) && isFirstWriteHostAllowed(s, fd, data)
+}
+
+// isWriteFdAllowed decides whether the tracee may write to fd.
+func isWriteFdAllowed(s Syscall, fd int32) bool {
+	isStdStream := s.IsStandardStream(fd)
 	if isStdStream {
 		return true
 	}
-	isSocket := args.IsSocket(s.TraceePID, fd)
-	if (runtime.Get().NetworkAllowServer || runtime.Get().NetworkAllowClient || runtime.Get().LocalSocketsAllow) && isSocket {
-		return true
-	}
-	isFile := args.IsFile(s.TraceePID, fd)
-	if runtime.Get().FileSystemAllowWrite && isFile {
-		return true
-	}
-	isPipe := args.IsPipe(s.TraceePID, fd)
-	if isPipe {
-		return true
-	}
 
-	isEventFd := args.FdType(s.TraceePID, fd) == args.FDAnonEvent
-	if isEventFd {
-		println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", "write", fd, isEventFd))
+	switch fdType := s.FdType(fd); fdType {
+	case args.FDSocket:
+		return s.config().NetworkAllowServer || s.config().NetworkAllowClient || s.config().NetworkAllowLoopback || s.config().LocalSocketsAllow
+	case args.FDFile:
+		return s.config().FileSystemAllowWrite
+	case args.FDPipe:
+		return true
+	case args.FDAnonEvent:
+		println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", "write", fd, true))
 		return true
 	}
 
@@ -0,0 +1,65 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
+)
+
+func TestIsWriteAllowedStandardStreams(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	f, err := os.Create(t.TempDir() + "/out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stdout, err := args.FdFileID(os.Getpid(), int32(w.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fd 1 of the tracee first is the stdout pipe, then a file is
+	// dup2'd onto it
+	redirected := args.NewFdTable()
+	redirected.Set(1, args.FdInfo{Type: args.FDFile, Path: f.Name()})
+	started := args.NewFdTable()
+	started.Set(1, args.FdInfo{Type: args.FDPipe, Stdio: true})
+
+	tests := []struct {
+		name string
+		fd   uintptr
+		fds  *args.FdTable
+		want bool
+	}{
+		{"stdout pipe from /proc", w.Fd(), nil, true},
+		{"file from /proc", f.Fd(), nil, false},
+		{"stdout from fd table", 1, started, true},
+		{"file dup2'd onto stdout", 1, redirected, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Syscall{
+				TraceePID: os.Getpid(),
+				Fds:       tt.fds,
+				Stdio:     []args.FileID{stdout},
+				Config:    &runtime.Config{},
+			}
+			s.Args[0] = SyscallArgument{Value: tt.fd}
+
+			if got := IsWriteAllowed(s, true); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package syscalls
+
+// IsXattrAllowed checks getxattr, setxattr, listxattr and removexattr, which
+// take the path as arg 0.
+func IsXattrAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowed(s, 0, -1)
+}
+
+// IsLxattrAllowed checks lgetxattr, lsetxattr, llistxattr and lremovexattr,
+// which do not follow a symlink in the last component of the path at arg 0.
+func IsLxattrAllowed(s Syscall, isEnter bool) bool {
+	return PathIsAllowedNoFollow(s, 0, -1)
+}
@@ -51,7 +51,7 @@ func PrintTraces(w io.Writer) EventCallback {
 // SysCallEnter is called each time a system call enter event happens.
 func SysCallEnter(t Task, s *SyscallEvent) string {
 	pid := t.(*process).pid
-	name, _ := sec.ScmpSyscall(s.Regs.Orig_rax).GetName()
+	name, _ := sec.ScmpSyscall(s.Sysno).GetName()
 	if name == "access" {
 		pathAddr := s.Args[0].Pointer()
 		var path string
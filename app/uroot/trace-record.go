@@ -25,42 +25,46 @@ type TraceRecord struct {
 func (t *TraceRecord) syscallStop(p *process) error {
 	t.Syscall = &SyscallEvent{}
 
-	if err := unix.PtraceGetRegs(p.pid, &t.Syscall.Regs); err != nil {
+	if err := getRegs(p.pid, &t.Syscall.Regs); err != nil {
 		return err
 	}
-
-	// name, _ := sec.ScmpSyscall(t.Syscall.Regs.Orig_rax).GetName()
 	t.Syscall.FillArgs()
 
-	// if name == "openat" {
-	// 	var s string
-	// 	var b [1]byte
-	// 	addr := t.Syscall.Args[1].Pointer()
-	// 	for len(s) < 1024 {
-	// 		if _, err := p.Read(addr, b[:]); err != nil {
-	// 			break
-	// 		}
-	// 		if b[0] == 0 {
-	// 			break
-	// 		}
-	// 		s = s + string(b[:])
-	// 		addr++
-	// 	}
+	// PTRACE_GET_SYSCALL_INFO tells reliably whether this is an entry or
+	// an exit stop. Before Linux 5.3, we have to keep track of that
+	// ourselves, which goes wrong if seccomp injects an exit stop without
+	// an entry stop.
+	info, err := getSyscallInfo(p.pid)
+	if err != nil || info.Op == unix.PTRACE_SYSCALL_INFO_NONE {
+		info = nil
+	}
+	isEnter := p.lastSyscallStop.Event != SyscallEnter
+	if info != nil {
+		isEnter = info.isEntry()
+	}
 
-	// 	fmt.Printf("name %s arg %s", name, s)
-	// } else {
-	// 	fmt.Printf("name %s args %#v", name, t.Syscall.Args)
-	// }
+	if isEnter {
+		t.Event = SyscallEnter
+		if info != nil {
+			t.Syscall.fillInfo(info)
+		}
+		p.lastSyscallStop = t
+		return nil
+	}
 
-	// TODO: the ptrace man page mentions that seccomp can inject a
-	// syscall-exit-stop without a preceding syscall-enter-stop. Detect
-	// that here, however you'd detect it...
-	if p.lastSyscallStop.Event == SyscallEnter {
-		t.Event = SyscallExit
-		t.Syscall.FillRet()
-		t.Syscall.Duration = time.Since(p.lastSyscallStop.Time)
+	t.Event = SyscallExit
+	// Registers might have been overwritten with the result, so the exit
+	// is described by the syscall of its entry.
+	if enter := p.lastSyscallStop; enter.Event == SyscallEnter && enter.Syscall != nil {
+		t.Syscall.Arch = enter.Syscall.Arch
+		t.Syscall.Sysno = enter.Syscall.Sysno
+		t.Syscall.Args = enter.Syscall.Args
+		t.Syscall.Duration = t.Time.Sub(enter.Time)
+	}
+	if info != nil {
+		t.Syscall.fillInfo(info)
 	} else {
-		t.Event = SyscallEnter
+		t.Syscall.FillRet()
 	}
 	p.lastSyscallStop = t
 	return nil
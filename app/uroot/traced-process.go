@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
 	"golang.org/x/sys/unix"
 )
 
@@ -16,6 +17,25 @@ type process struct {
 	// syscall-enter-stop or syscall-exit-stop. You gotta keep track of
 	// that shit your own self.
 	lastSyscallStop *TraceRecord
+
+	// fds is the shadow fd table, which is shared with the processes
+	// created with CLONE_FILES, e.g. the threads of a process.
+	fds *args.FdTable
+
+	// injection is set while the process makes a syscall injected by the
+	// gatekeeper.
+	injection *syscallInjection
+}
+
+// syscallInjection describes how to resume a process once the syscall
+// injected by the gatekeeper returned.
+type syscallInjection struct {
+	// restore are the registers to continue the process with.
+	restore unix.PtraceRegs
+	// signal is injected when continuing the process.
+	signal unix.Signal
+	// fds are still to be closed by further injected syscalls.
+	fds []int32
 }
 
 // Name implements Task.Name.
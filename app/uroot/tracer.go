@@ -3,11 +3,12 @@ package uroot
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot/syscalls"
 	"github.com/cuandari/lib/app/uroot/syscalls/args"
 	sec "github.com/seccomp/libseccomp-golang"
@@ -23,9 +24,43 @@ func (e *ExitEventError) Error() string {
 	return fmt.Sprintf("Exited with status %d and signal %s", e.ExitCode, e.Signal)
 }
 
+// waitPollInterval bounds how long the tracer sleeps between polling its
+// tracees if no SIGCHLD is received.
+const waitPollInterval = 50 * time.Millisecond
+
 type tracer struct {
+	session   *Session
 	processes map[int]*process
 	callback  []EventCallback
+
+	// childEvents receives SIGCHLD, which the kernel sends whenever a
+	// tracee stops or exits.
+	childEvents chan os.Signal
+
+	// done is closed once the tracees should be detached. It is nil for
+	// tracees started by the gatekeeper, which cannot be detached.
+	done <-chan struct{}
+
+	// fdsEnforced is set once the shadow fd tables describe the tracees
+	// under enforcement, see rebuildFdTables.
+	fdsEnforced bool
+}
+
+func newTracer(session *Session, callback []EventCallback) *tracer {
+	t := &tracer{
+		session:     session,
+		processes:   make(map[int]*process),
+		callback:    callback,
+		childEvents: make(chan os.Signal, 1),
+		fdsEnforced: session.IsEnforced(),
+	}
+	signal.Notify(t.childEvents, unix.SIGCHLD)
+	return t
+}
+
+// close stops listening for SIGCHLD.
+func (t *tracer) close() {
+	signal.Stop(t.childEvents)
 }
 
 // SignalEvent is a signal that was delivered to the process.
@@ -42,9 +77,10 @@ func (t *tracer) call(p *process, rec *TraceRecord) {
 	}
 }
 
-func (t *tracer) addProcess(pid int, event EventType) {
+func (t *tracer) addProcess(pid int, event EventType, fds *args.FdTable) {
 	t.processes[pid] = &process{
 		pid: pid,
+		fds: fds,
 		lastSyscallStop: &TraceRecord{
 			Event: event,
 			Time:  time.Now(),
@@ -54,37 +90,23 @@ func (t *tracer) addProcess(pid int, event EventType) {
 
 func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 	for {
-		// TODO: we cannot have any other children. I'm not sure this
-		// is actually solvable: if we used a session or process group,
-		// a tracee process's usage of them would mess up our accounting.
-		//
-		// If we just ignored wait's of processes that we're not
-		// tracing, we'll be messing up other stuff in this program
-		// waiting on those.
-		//
-		// To actually encapsulate this library in a packge, we could
-		// do one of two things:
-		//
-		//   1) fork from the parent in order to be able to trace
-		//      children correctly. Then, a user of this library could
-		//      actually independently trace two different processes.
-		//      I don't know if that's worth doing.
-		//   2) have one goroutine per process, and call wait4
-		//      individually on each process we expect. We gotta check
-		//      if each has to be tied to an OS thread or not.
-		//
-		// The latter option seems much nicer.
-		pid, status, err := wait(-1)
-		if err == unix.ECHILD {
+		pid, status, err := t.wait()
+		if err == errDetach {
+			t.detachAll()
+			cancelFunc(&ExitEventError{})
+			return
+		} else if err == unix.ECHILD {
 			fmt.Printf("All watched processes died: %s. This is not related to the gatekeeper.\n", err.Error())
 			cancelFunc(&ExitEventError{
 				ExitCode: 3,
 			})
+			return
 		} else if err != nil {
 			fmt.Printf("Unable to wait for processed to stop and intercept syscall: %s. Exiting\n", err.Error())
 			cancelFunc(&ExitEventError{
 				ExitCode: 3,
 			})
+			return
 		}
 
 		// Which process was stopped?
@@ -120,9 +142,13 @@ func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 			// It allows us to distinguish syscall-stops from regular
 			// SIGTRAPs (e.g. sent by tkill(2)).
 			case syscall.SIGTRAP | 0x80:
-				if !GetIsGatekeeperEnforced() {
+				if !t.session.IsEnforced() {
 					break
 				}
+				if !t.fdsEnforced {
+					t.rebuildFdTables()
+					t.fdsEnforced = true
+				}
 
 				if err := rec.syscallStop(p); err != nil {
 					if strings.Contains(err.Error(), "no such process") {
@@ -139,175 +165,46 @@ func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 					}
 				}
 
-				rax := rec.Syscall.Regs.Orig_rax
-				name, err := sec.ScmpSyscall(rec.Syscall.Sysno).GetName()
-				isExitEvent := rec.Event == SyscallExit
-				if err != nil {
+				// The process returns from a syscall injected by the
+				// gatekeeper, which is not subject to the policy.
+				if p.injection != nil {
+					injectSignal = t.finishInjection(p, rec, cancelFunc)
+					break
+				}
+
+				// The policy is decided once at entry. Exit stops only
+				// verify the result of syscalls that were allowed.
+				if rec.Event != SyscallEnter {
+					injectSignal = t.verifyResult(p, rec, cancelFunc)
+					if injectSignal == 0 && p.injection == nil {
+						trackFds(p, rec)
+						t.auditResult(p, rec)
+					}
+					break
+				}
+
+				sysno := uint64(rec.Syscall.Sysno)
+				name, err := sec.ScmpSyscall(rec.Syscall.Sysno).GetNameByArch(rec.Syscall.Arch)
+				if !t.session.isArchAllowed(rec.Syscall.Arch) {
+					// The syscall number has a different meaning in
+					// the table of a foreign ABI and cannot be checked
+					// by name.
+					fmt.Printf("Syscall %d of foreign ABI %s not allowed\n", sysno, rec.Syscall.Arch)
+					injectSignal = t.deny(p, rec, cancelFunc)
+				} else if err != nil {
 					// ending up here, we were not able to get the name of the syscall
 					// suspicious because the process might not have been stopped by a syscall
 					// but something else, so printing here for now while keeping the tracee
 					// up and running
-					fmt.Printf("Unknown syscall detected for exit->%t: %s %d\n", isExitEvent, err.Error(), rax)
+					fmt.Printf("Unknown syscall detected: %s %d\n", err.Error(), sysno)
 				} else {
-					addSyscallToCollection(rax, name)
-
-					allow := allowSyscall(name)
+					t.session.addSyscallToCollection(sysno, name)
 
-					// Family-aware gating for socket/connect via helpers
-					// Build unified syscall context for helpers
-					var sargs syscalls.SyscallArguments
-					for i := 0; i < len(sargs); i++ {
-						sargs[i] = syscalls.SyscallArgument{Value: rec.Syscall.Args[i].Value}
-					}
-					s := syscalls.Syscall{
-						Args:      sargs,
-						TraceePID: p.pid,
-						Reader: func(addr syscalls.Addr, v interface{}) (int, error) {
-							return p.Read(Addr(addr), v)
-						},
-					}
-					switch {
-					case name == "socket":
-						allow = syscalls.IsSocketAllowed(s, rec.Event == SyscallEnter)
-					case name == "connect":
-						allow = syscalls.IsConnectAllowed(s, rec.Event == SyscallEnter)
-					case name == "open":
-						allow = syscalls.IsOpenAllowed(s, rec.Event == SyscallEnter)
-					case name == "openat":
-						allow = syscalls.IsOpenAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "openat2":
-						allow = syscalls.IsOpenAt2Allowed(s, rec.Event == SyscallEnter)
-					case name == "mkdir":
-						allow = syscalls.IsMkdirAllowed(s, rec.Event == SyscallEnter)
-					case name == "mkdirat":
-						allow = syscalls.IsMkdirAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "rmdir":
-						allow = syscalls.IsRmdirAllowed(s, rec.Event == SyscallEnter)
-					case name == "unlink":
-						allow = syscalls.IsUnlinkAllowed(s, rec.Event == SyscallEnter)
-					case name == "unlinkat":
-						allow = syscalls.IsUnlinkAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "rename":
-						allow = syscalls.IsRenameAllowed(s, rec.Event == SyscallEnter)
-					case name == "renameat":
-						allow = syscalls.IsRenameAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "link":
-						allow = syscalls.IsLinkAllowed(s, rec.Event == SyscallEnter)
-					case name == "linkat":
-						allow = syscalls.IsLinkAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "symlink":
-						allow = syscalls.IsSymlinkAllowed(s, rec.Event == SyscallEnter)
-					case name == "symlinkat":
-						allow = syscalls.IsSymlinkAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "access":
-						// access(const char *pathname, int mode) : pathname is arg 0
-						allow = syscalls.IsAccessAllowed(s, rec.Event == SyscallEnter)
-					case name == "faccessat":
-						// faccessat(int dirfd, const char *pathname, int mode)
-						// pathname is arg 1, dirfd is arg 0
-						allow = syscalls.IsFaccessAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "faccessat2":
-						// faccessat2(int dirfd, const char *pathname, int mode, int flags)
-						// pathname is arg 1, dirfd is arg 0
-						allow = syscalls.IsFaccessAtAllowed(s, rec.Event == SyscallEnter)
-					case name == "write" || name == "writev" || name == "send" || name == "sendmsg" || name == "sendmmsg" || name == "sendto":
-						syscallArgs := rec.Syscall.Args
-						fd := syscallArgs[0].Int()
-
-						allow = syscalls.IsWriteAllowed(s, rec.Event == SyscallEnter)
-
-						// {
-						//     isEventFd := args.FdType(p.pid, fd) == args.FDAnonEvent
-						//     println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", name, fd, isEventFd))
-						//     allow = isEventFd
-						// }
-
-						if !allow {
-							fdType := args.FdType(p.pid, fd)
-							println(fmt.Sprintf("Trying to write to fd %d which is of type %s", fd, fdType))
-						}
-
-					case name == "read" || name == "readv" || name == "recv" || name == "recvfrom" || name == "recvmsg" || name == "recvmmsg":
-						syscallArgs := rec.Syscall.Args
-						fd := syscallArgs[0].Int()
-
-						allow = syscalls.IsReadAllowed(s, rec.Event == SyscallEnter)
-
-						// if !allow {
-						//     isEventFd := args.FdType(p.pid, fd) == args.FDAnonEvent
-						//     println(fmt.Sprintf("Trying to %s from fd %d which is a anon eventfd %t", name, fd, isEventFd))
-						//     allow = isEventFd
-						// }
-
-						if !allow {
-							fdType := args.FdType(p.pid, fd)
-							println(fmt.Sprintf("Trying to read from fd %d which is of type %s", fd, fdType))
-						}
-					case name == "shutdown":
-						// shutdown(int sockfd, int how)
-						syscallArgs := rec.Syscall.Args
-						fd := syscallArgs[0].Int()
-
-						allow = syscalls.IsShutdownAllowed(s, rec.Event == SyscallEnter)
-
-						if !allow {
-							fdType := args.FdType(p.pid, fd)
-							println(fmt.Sprintf("Trying to shutdown fd %d which is of type %s", fd, fdType))
-						}
-					case name == "close":
-						// close(int fd)
-						syscallArgs := rec.Syscall.Args
-						fd := syscallArgs[0].Int()
-
-						allow = syscalls.IsCloseAllowed(s, rec.Event == SyscallEnter)
-
-						if !allow {
-							fdType := args.FdType(p.pid, fd)
-							println(fmt.Sprintf("Trying to close fd %d which is of type %s", fd, fdType))
-						}
-					}
+					allow := t.session.isSyscallAllowed(name, newSyscall(p, rec), true)
 
 					if !allow {
 						fmt.Println("Syscall not allowed:", name)
-						if runtime.Get().SyscallsDenyTargetIfNotAllowed {
-							fmt.Println("Syscall not allowed. However we don't have permission to kill")
-
-							// https://stackoverflow.com/a/6469069/13163094
-							switch rec.Event {
-							case SyscallEnter:
-								// Make sure the syscall is not valid anymore by changing the value that identifies it
-								rec.Syscall.Regs.Orig_rax = ^uint64(0)
-								rec.Syscall.Regs.Rax = ^uint64(0)
-								// In the context of seccomp, SIGSYS is the primary signal used to indicate a policy violation.
-								// When a seccomp filter is in place and a process attempts a disallowed system call, the kernel
-								// intercepts the call and sends SIGSYS to the process, preventing the system call from executing.
-								injectSignal = unix.SIGSYS
-							case SyscallExit:
-								// Syscall Exit: The kernel sets register rax to the result of the syscall. This is typically 0 for success or -1 (represented as the maximum unsigned integer value) for an error.
-								rec.Syscall.Regs.Orig_rax = ^uint64(0)
-								rec.Syscall.Regs.Rax = ^uint64(0)
-								// Syscall Exit: If Rax indicates an error (-1), Rdx will typically contain the specific error code (the errno) explaining the reason for the failure.
-								rec.Syscall.Regs.Rdx = uint64(unix.EPERM) // Set errno
-								// In the context of seccomp, SIGSYS is the primary signal used to indicate a policy violation.
-								// When a seccomp filter is in place and a process attempts a disallowed system call, the kernel
-								// intercepts the call and sends SIGSYS to the process, preventing the system call from executing.
-								injectSignal = unix.SIGSYS
-							default:
-								// Don't send SIGKILL; let the process continue with the simulated error return
-								injectSignal = 0
-							}
-
-							// Set registers before continuing with the syscall exit.
-							if err := unix.PtraceSetRegs(p.pid, &rec.Syscall.Regs); err != nil {
-								fmt.Printf("Unable to set syscall params and args: %s. Exiting\n", err.Error())
-								cancelFunc(&ExitEventError{
-									ExitCode: 3,
-								})
-							}
-
-						} else {
-							injectSignal = syscall.SIGKILL
-						}
+						injectSignal = t.deny(p, rec, cancelFunc)
 					}
 				}
 
@@ -341,6 +238,10 @@ func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 			case syscall.SIGTRAP:
 				switch tc := status.TrapCause(); tc {
 				// This is a PTRACE_EVENT stop.
+				// A seized tracee was interrupted or a child of a
+				// seized tracee was attached. Nothing to report.
+				case unix.PTRACE_EVENT_STOP:
+
 				case unix.PTRACE_EVENT_CLONE, unix.PTRACE_EVENT_FORK, unix.PTRACE_EVENT_VFORK:
 					childPID, err := unix.PtraceGetEventMsg(pid)
 					if err != nil {
@@ -349,9 +250,15 @@ func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 							ExitCode: 3,
 						})
 					}
+					// Children created with CLONE_FILES share the fd
+					// table, others get a copy.
+					fds := p.fds
+					if !sharesFdTable(p, int(childPID)) {
+						fds = fds.Clone()
+					}
 					// The first event will be an Enter syscall, so
 					// set the last event to an exit.
-					t.addProcess(int(childPID), SyscallExit)
+					t.addProcess(int(childPID), SyscallExit, fds)
 
 					rec.Event = NewChild
 					rec.NewChild = &NewChildEvent{
@@ -430,8 +337,225 @@ func (t *tracer) runLoop(cancelFunc context.CancelCauseFunc) {
 	}
 }
 
-func wait(pid int) (int, unix.WaitStatus, error) {
-	var w unix.WaitStatus
-	pid, err := unix.Wait4(pid, &w, 0, nil)
-	return pid, w, err
+// rebuildFdTables replaces the shadow fd tables of all tracees once the
+// policy is enforced. Syscalls are not inspected before, so the tables miss
+// the fds created, duplicated and closed until then, e.g. a file dup2'ed onto
+// stdout would still be recorded as the standard stream. The new tables only
+// contain the standard streams that still refer to the objects the tracee
+// was started with, other fds are looked up in /proc. Threads sharing a table
+// keep sharing the new one.
+func (t *tracer) rebuildFdTables() {
+	rebuilt := make(map[*args.FdTable]*args.FdTable)
+	for pid, p := range t.processes {
+		fds, ok := rebuilt[p.fds]
+		if !ok {
+			fds = args.NewStdioFdTable(pid, t.session.stdio)
+			rebuilt[p.fds] = fds
+		}
+		p.fds = fds
+	}
+}
+
+// deny prevents the syscall the stopped tracee p is about to enter from
+// taking effect and returns the signal to inject when continuing it.
+// Depending on the configuration, the tracee is killed or sees the syscall
+// failing.
+func (t *tracer) deny(p *process, rec *TraceRecord, cancelFunc context.CancelCauseFunc) unix.Signal {
+	if !t.session.config.SyscallsDenyTargetIfNotAllowed {
+		return syscall.SIGKILL
+	}
+	fmt.Println("Syscall not allowed. However we don't have permission to kill")
+
+	// https://stackoverflow.com/a/6469069/13163094
+	// The tracee sees the syscall failing with EPERM. Make sure the syscall
+	// is not executed by replacing the number that identifies it.
+	setSyscallReturn(&rec.Syscall.Regs, errnoReturn(unix.EPERM))
+	if err := skipSyscall(p.pid, &rec.Syscall.Regs); err != nil {
+		fmt.Printf("Unable to set syscall params and args: %s. Exiting\n", err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 3,
+		})
+	}
+
+	// In the context of seccomp, SIGSYS is the primary signal used to indicate a policy violation.
+	// When a seccomp filter is in place and a process attempts a disallowed system call, the kernel
+	// intercepts the call and sends SIGSYS to the process, preventing the system call from executing.
+	return unix.SIGSYS
+}
+
+// newSyscall builds the unified syscall context for helpers from the
+// syscall the stopped tracee p is in.
+func newSyscall(p *process, rec *TraceRecord) syscalls.Syscall {
+	var sargs syscalls.SyscallArguments
+	for i := 0; i < len(sargs); i++ {
+		sargs[i] = syscalls.SyscallArgument{Value: rec.Syscall.Args[i].Value}
+	}
+	return syscalls.Syscall{
+		Args:      sargs,
+		TraceePID: p.pid,
+		Fds:       p.fds,
+		Reader: func(addr syscalls.Addr, v interface{}) (int, error) {
+			return p.Read(Addr(addr), v)
+		},
+	}
+}
+
+// auditResult records the connection the tracee p accepted, if any, in the
+// network audit log.
+func (t *tracer) auditResult(p *process, rec *TraceRecord) {
+	if t.session.audit == nil || rec.Syscall.Errno != 0 {
+		return
+	}
+	name, err := sec.ScmpSyscall(rec.Syscall.Sysno).GetNameByArch(rec.Syscall.Arch)
+	if err != nil || (name != "accept" && name != "accept4") {
+		return
+	}
+	sc := newSyscall(p, rec)
+	sc.Audit = t.session.audit
+	syscalls.AuditAccept(sc, name, int32(rec.Syscall.Ret[0].Value))
+}
+
+// verifyResult checks the result of a syscall that was allowed at entry and
+// returns the signal to inject when continuing the tracee p. Paths can be
+// swapped, e.g. by replacing a directory with a symlink, after they were
+// checked at entry. The file that was actually opened, and the fds that were
+// actually received, are therefore verified again once the syscall returned.
+func (t *tracer) verifyResult(p *process, rec *TraceRecord, cancelFunc context.CancelCauseFunc) unix.Signal {
+	if rec.Syscall.Errno != 0 {
+		return 0
+	}
+	name, err := sec.ScmpSyscall(rec.Syscall.Sysno).GetNameByArch(rec.Syscall.Arch)
+	if err != nil {
+		return 0
+	}
+	if name == "recvmsg" || name == "recvmmsg" {
+		return t.verifyReceivedFds(p, rec, name == "recvmmsg", cancelFunc)
+	}
+	fd := int(int64(rec.Syscall.Ret[0].Value))
+	if fd < 0 || t.session.isSyscallResultAllowed(name, newSyscall(p, rec), fd) {
+		return 0
+	}
+
+	fmt.Printf("Path of fd %d opened by %s is not allowed\n", fd, name)
+	return t.closeFds(p, rec, []int32{int32(fd)}, cancelFunc)
+}
+
+// verifyReceivedFds checks the fds passed to the tracee p with SCM_RIGHTS by
+// its recvmsg or recvmmsg. A control buffer can be added to the messages
+// after they were checked at entry, so fds that arrived on a socket they may
+// not be passed over are closed.
+func (t *tracer) verifyReceivedFds(p *process, rec *TraceRecord, mmsg bool, cancelFunc context.CancelCauseFunc) unix.Signal {
+	sc := newSyscall(p, rec)
+	sc.Config = t.session.config
+	rights, err := syscalls.ReceivedRights(sc, mmsg, int(rec.Syscall.Ret[0].Value))
+	if (err == nil && len(rights) == 0) || syscalls.IsReceivedRightsAllowed(sc) {
+		return 0
+	}
+	if err != nil {
+		fmt.Printf("Unable to read the fds received by pid %d: %s\n", p.pid, err.Error())
+		return syscall.SIGKILL
+	}
+
+	fmt.Printf("Fds %v received by pid %d are not allowed\n", rights, p.pid)
+	return t.closeFds(p, rec, rights, cancelFunc)
+}
+
+// closeFds makes the tracee p, stopped at the exit of a syscall, close fds
+// before it sees the syscall failing with EPERM, and returns the signal to
+// inject when continuing it. The tracee must not keep the fds, so it calls
+// close once per fd.
+func (t *tracer) closeFds(p *process, rec *TraceRecord, fds []int32, cancelFunc context.CancelCauseFunc) unix.Signal {
+	if !t.session.config.SyscallsDenyTargetIfNotAllowed || rec.Syscall.Arch != nativeArch {
+		return syscall.SIGKILL
+	}
+
+	restore := rec.Syscall.Regs
+	setSyscallReturn(&restore, errnoReturn(unix.EPERM))
+	p.injection = &syscallInjection{
+		restore: restore,
+		signal:  unix.SIGSYS,
+		fds:     fds,
+	}
+	return t.injectClose(p, rec, cancelFunc)
+}
+
+// injectClose makes the tracee p, stopped at the exit of a syscall, call
+// close with the next fd of its injection.
+func (t *tracer) injectClose(p *process, rec *TraceRecord, cancelFunc context.CancelCauseFunc) unix.Signal {
+	fd := p.injection.fds[0]
+	p.injection.fds = p.injection.fds[1:]
+	regs := rec.Syscall.Regs
+	rewindSyscall(&regs, unix.SYS_CLOSE, uintptr(fd))
+	if err := setRegs(p.pid, &regs); err != nil {
+		fmt.Printf("Unable to close fd %d of pid %d: %s. Exiting\n", fd, p.pid, err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 3,
+		})
+		return syscall.SIGKILL
+	}
+	return 0
+}
+
+// finishInjection continues the tracee p in a syscall injected by
+// verifyResult. Once the injected syscalls returned, the registers of the
+// original syscall are restored.
+func (t *tracer) finishInjection(p *process, rec *TraceRecord, cancelFunc context.CancelCauseFunc) unix.Signal {
+	if rec.Event == SyscallEnter {
+		return 0
+	}
+	if len(p.injection.fds) > 0 {
+		return t.injectClose(p, rec, cancelFunc)
+	}
+
+	injection := p.injection
+	p.injection = nil
+	if err := setRegs(p.pid, &injection.restore); err != nil {
+		fmt.Printf("Unable to restore registers of pid %d: %s. Exiting\n", p.pid, err.Error())
+		cancelFunc(&ExitEventError{
+			ExitCode: 3,
+		})
+		return syscall.SIGKILL
+	}
+	return injection.signal
+}
+
+// wait waits until one of the traced processes changed its state. Only the
+// pids of this tracer are waited for. Exit statuses of other children of the
+// gatekeeper and the tracees of other sessions are left alone, which allows
+// several tracers to run in one process.
+func (t *tracer) wait() (int, unix.WaitStatus, error) {
+	for {
+		if len(t.processes) < 1 {
+			return -1, 0, unix.ECHILD
+		}
+
+		select {
+		case <-t.done:
+			return -1, 0, errDetach
+		default:
+		}
+
+		for pid := range t.processes {
+			var w unix.WaitStatus
+			wpid, err := unix.Wait4(pid, &w, unix.WNOHANG|unix.WALL, nil)
+			if err == unix.ECHILD {
+				// The process was reaped by someone else and no
+				// events can be received for it anymore.
+				fmt.Printf("Lost track of pid %d: %s\n", pid, err.Error())
+				delete(t.processes, pid)
+				continue
+			} else if err != nil && err != unix.EINTR {
+				return -1, 0, err
+			}
+			if wpid == pid {
+				return pid, w, nil
+			}
+		}
+
+		select {
+		case <-t.childEvents:
+		case <-t.done:
+		case <-time.After(waitPollInterval):
+		}
+	}
 }
@@ -13,6 +13,7 @@ package uroot
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -25,6 +26,7 @@ import (
 
 	runtimeConfig "github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot/stdout"
+	"github.com/cuandari/lib/app/uroot/syscalls/args"
 	"github.com/cuandari/lib/app/utils"
 
 	"golang.org/x/sys/unix"
@@ -55,7 +57,11 @@ type Task interface {
 	Name() string
 }
 
-func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Context, error) {
+// Exec starts bin with args and gatekeeps it with the session. The output of
+// the tracee is forwarded to the gatekeeper's stdout and stderr, which also
+// drives the log match trigger of the configuration.
+func (s *Session) Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Context, error) {
+	conf := s.config
 	executable, err := exec.LookPath(bin)
 	if err != nil {
 		cancelledContext, cancel := context.WithCancelCause(context.Background())
@@ -65,7 +71,7 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 		return nil, cancelledContext, fmt.Errorf("unable to find executable %s: %w", bin, err)
 	}
 	cmd := exec.CommandContext(ctx, executable, args...)
-	cmd.Env = getEnv(os.Getpid())
+	cmd.Env = getEnv(os.Getpid(), conf)
 	cmd.WaitDelay = 5 * time.Second
 	cmd.Cancel = func() error {
 		return syscall.Kill(cmd.Process.Pid, syscall.SIGTERM)
@@ -73,17 +79,17 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 	// Forward parent's stdin to the child so piped input reaches the tracee.
 	cmd.Stdin = os.Stdin
 
-	if runtimeConfig.Get().ExecutionMode == runtimeConfig.EXECUTION_MODE_TRACE {
+	if conf.ExecutionMode == runtimeConfig.EXECUTION_MODE_TRACE {
 		// nolint:gosimple
 		go func() {
 			<-ctx.Done()
 			f, _ := os.Create("gk-syscalls-before-enforce.txt")
-			for k := range syscallsBeforeEnforce {
+			for k := range s.SyscallsCollectedBeforeEnforce() {
 				_, _ = f.WriteString(k)
 				_, _ = f.WriteString("\n")
 			}
 			f, _ = os.Create("gk-syscalls-after-enforce.txt")
-			for k := range syscallsAfterEnforce {
+			for k := range s.SyscallsCollectedAfterEnforce() {
 				_, _ = f.WriteString(k)
 				_, _ = f.WriteString("\n")
 			}
@@ -100,13 +106,11 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 		return nil, cancelledContext, fmt.Errorf("error creating stdout pipe: %w", err)
 	}
 
-	if runtimeConfig.Get().EnforceOnStartup {
-		enforceGatekeeper()
-
+	if conf.EnforceOnStartup {
 		stdout.PipeStdOut(ctx, stdoutPipe)
 		// if we should enable the gatekeeper via log search string
 		// create another goroutine that keeps monitoring stdout
-	} else if runtimeConfig.Get().TriggerEnforceLogMatch != "" {
+	} else if conf.TriggerEnforceLogMatch != "" {
 		go func() {
 			scanner := bufio.NewScanner(stdoutPipe)
 			for scanner.Scan() {
@@ -124,9 +128,9 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 					_, _ = os.Stdout.WriteString(t)
 					_, _ = os.Stdout.WriteString("\n")
 
-					if strings.Contains(t, runtimeConfig.Get().TriggerEnforceLogMatch) {
+					if strings.Contains(t, conf.TriggerEnforceLogMatch) {
 						println("Enabling gatekeeper now because log search string was detected.")
-						enforceGatekeeper()
+						s.Enforce()
 						brkLoop = true
 					}
 				}
@@ -138,14 +142,14 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 
 			stdout.PipeStdOut(ctx, stdoutPipe)
 		}()
-	} else if runtimeConfig.Get().TriggerEnforceSignal != "" {
+	} else if conf.TriggerEnforceSignal != "" {
 		go func() {
 			signalChan := make(chan os.Signal, 1)
-			signal.Notify(signalChan, unix.SignalNum(runtimeConfig.Get().TriggerEnforceSignal))
+			signal.Notify(signalChan, unix.SignalNum(conf.TriggerEnforceSignal))
 
 			<-signalChan
 			println("Enabling gatekeeper now because signal was detected.")
-			enforceGatekeeper()
+			s.Enforce()
 
 			stdout.PipeStdOut(ctx, stdoutPipe)
 		}()
@@ -164,26 +168,59 @@ func Exec(ctx context.Context, bin string, args []string) (*exec.Cmd, context.Co
 	}
 	stdout.PipeStdErr(ctx, stderrPipe)
 
+	exitContext, err := s.Start(ctx, cmd, PrintTraces(os.Stdout))
+	if err != nil {
+		cancelledContext, cancel := context.WithCancelCause(context.Background())
+		cancel(&ExitEventError{
+			ExitCode: 12,
+		})
+		return nil, cancelledContext, err
+	}
+
+	return cmd, exitContext, nil
+}
+
+// Start starts c and gatekeeps it and its children with the backend of the
+// session configuration. The returned context is cancelled with an
+// *ExitEventError as cause once the tracee exited.
+//
+// recordCallback is called every time a process event happens.
+func (s *Session) Start(ctx context.Context, c *exec.Cmd, recordCallback ...EventCallback) (context.Context, error) {
+	isSeccompNotify := s.config.Backend == runtimeConfig.BACKEND_SECCOMP_NOTIFY
+	if isSeccompNotify && s.audit != nil {
+		return nil, errors.New("the network audit log requires the ptrace backend, which sees the connections accepted by the tracee")
+	}
+	if isSeccompNotify {
+		if err := s.wrapSeccompNotifyCommand(c); err != nil {
+			return nil, fmt.Errorf("unable to prepare seccomp-notify backend: %w", err)
+		}
+	}
+
 	exitContext, cancel := context.WithCancelCause(ctx)
 	go func() {
-		Strace(cmd, cancel, os.Stdout)
+		if isSeccompNotify {
+			s.SeccompNotify(c, cancel, recordCallback...)
+		} else {
+			s.Trace(c, cancel, recordCallback...)
+		}
 	}()
 
-	return cmd, exitContext, nil
+	return exitContext, nil
 }
 
 // Strace traces and prints process events for `c` and its children to `out`.
-func Strace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, out io.Writer) {
-	Trace(c, cancelFunc, PrintTraces(out))
+func (s *Session) Strace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, out io.Writer) {
+	s.Trace(c, cancelFunc, PrintTraces(out))
 }
 
 // Trace traces `c` and any children c clones.
 //
-// Only one trace can be active per process.
+// Every trace only waits for its own tracees, so several traces can be active
+// per process.
 //
 // recordCallback is called every time a process event happens with the process
 // in a stopped state.
-func Trace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, recordCallback ...EventCallback) {
+func (s *Session) Trace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, recordCallback ...EventCallback) {
 	if c.SysProcAttr == nil {
 		c.SysProcAttr = &syscall.SysProcAttr{}
 	}
@@ -194,56 +231,25 @@ func Trace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, recordCallback ...Ev
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
-	if err := c.Start(); err != nil {
-		fmt.Printf("Unable to start process %s\n", err.Error())
-		cancelFunc(&ExitEventError{
-			ExitCode: 2,
-		})
-	}
+	tracer := newTracer(s, recordCallback)
+	defer tracer.close()
 
-	tracer := &tracer{
-		processes: make(map[int]*process),
-		callback:  recordCallback,
+	var err error
+	if policy, ok := s.landlockPolicy(c.Path); ok {
+		err = startRestricted(c, policy)
+	} else {
+		err = startTraced(c)
 	}
-
-	// Start will fork, set PTRACE_TRACEME, and then execve. Once that
-	// happens, we should be stopped at the execve "exit". This wait will
-	// return at that exit point.
-	//
-	// The new task image has been loaded at this point, with us just about
-	// to jump into _start.
-	//
-	// It'd make sense to assume, but this stop is NOT a syscall-exit-stop
-	// of the execve. It is a signal-stop triggered at the end of execve,
-	// within the confines of the new task image.  This means the execve
-	// syscall args are not in their registers, and we can't print the
-	// exit.
-	//
-	// NOTE(chrisko): we could make it such that we can read the args of
-	// the execve. If we were to signal ourselves between PTRACE_TRACEME
-	// and execve, we'd stop before the execve and catch execve as a
-	// syscall-stop after. To do so, we have 3 options: (1) write a copy of
-	// stdlib exec.Cmd.Start/os.StartProcess with the change, or (2)
-	// upstreaming a change that would make it into the next Go version, or
-	// (3) use something other than *exec.Cmd as the API.
-	//
-	// A copy of the StartProcess logic would be tedious, an upstream
-	// change would take a while to get into Go, and we want this API to be
-	// easily usable. I think it's ok to sacrifice the execve for now.
-	if _, ws, err := wait(c.Process.Pid); err != nil {
-		fmt.Printf("Received error while waiting for pid %d to stop for ptrace injection: %s\n", c.Process.Pid, err.Error())
-		cancelFunc(&ExitEventError{
-			ExitCode: 2,
-		})
-		return
-	} else if ws.TrapCause() != 0 {
-		fmt.Printf("Expected pid %d to be stopped but got %#+v\n", c.Process.Pid, ws)
+	if err != nil {
+		fmt.Printf("Unable to start process %s: %s\n", c.Path, err.Error())
 		cancelFunc(&ExitEventError{
 			ExitCode: 2,
 		})
 		return
 	}
-	tracer.addProcess(c.Process.Pid, SyscallExit)
+
+	s.stdio = args.StdioIDs(c.Process.Pid)
+	tracer.addProcess(c.Process.Pid, SyscallExit, args.NewStdioFdTable(c.Process.Pid, s.stdio))
 
 	if err := unix.PtraceSetOptions(c.Process.Pid,
 		// Tells ptrace to generate a SIGTRAP signal immediately before a new program is executed with the execve system call.
@@ -274,9 +280,55 @@ func Trace(c *exec.Cmd, cancelFunc context.CancelCauseFunc, recordCallback ...Ev
 	tracer.runLoop(cancelFunc)
 }
 
-func getEnv(pid int) []string {
+// startTraced starts c with PTRACE_TRACEME and waits until it stopped after
+// execve. The calling thread becomes the tracer of c.
+func startTraced(c *exec.Cmd) error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	// Start will fork, set PTRACE_TRACEME, and then execve. Once that
+	// happens, we should be stopped at the execve "exit". This wait will
+	// return at that exit point.
+	//
+	// The new task image has been loaded at this point, with us just about
+	// to jump into _start.
+	//
+	// It'd make sense to assume, but this stop is NOT a syscall-exit-stop
+	// of the execve. It is a signal-stop triggered at the end of execve,
+	// within the confines of the new task image.  This means the execve
+	// syscall args are not in their registers, and we can't print the
+	// exit.
+	//
+	// NOTE(chrisko): we could make it such that we can read the args of
+	// the execve. If we were to signal ourselves between PTRACE_TRACEME
+	// and execve, we'd stop before the execve and catch execve as a
+	// syscall-stop after. To do so, we have 3 options: (1) write a copy of
+	// stdlib exec.Cmd.Start/os.StartProcess with the change, or (2)
+	// upstreaming a change that would make it into the next Go version, or
+	// (3) use something other than *exec.Cmd as the API.
+	//
+	// A copy of the StartProcess logic would be tedious, an upstream
+	// change would take a while to get into Go, and we want this API to be
+	// easily usable. I think it's ok to sacrifice the execve for now.
+	var ws unix.WaitStatus
+	if _, err := unix.Wait4(c.Process.Pid, &ws, 0, nil); err != nil {
+		return fmt.Errorf("received error while waiting for pid %d to stop for ptrace injection: %w", c.Process.Pid, err)
+	} else if ws.TrapCause() != 0 {
+		return fmt.Errorf("expected pid %d to be stopped but got %#+v", c.Process.Pid, ws)
+	}
+	return nil
+}
+
+func getEnv(pid int, conf *runtimeConfig.Config) []string {
 	envVar := fmt.Sprintf("GATEKEEPER_PID=%d", pid)
 	env := os.Environ()
 	env = append(env, envVar)
+	if conf.NetworkEgressProxy != "" {
+		// Clients differ in whether they read the upper or the lower
+		// case variable. Later entries replace earlier ones.
+		proxy := "http://" + conf.NetworkEgressProxy
+		env = append(env, "HTTPS_PROXY="+proxy, "https_proxy="+proxy)
+	}
 	return env
 }
@@ -0,0 +1,18 @@
+package utils
+
+import "strings"
+
+// MatchDomain checks if name is one of domains or a subdomain of one. Names
+// are compared in lower case without a trailing dot, and a leading "*." of a
+// domain is ignored.
+func MatchDomain(domains []string, name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(d), "."), "*.")
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestMatchDomain(t *testing.T) {
+	allowed := []string{"example.com", "*.pypi.org", "Golang.org."}
+	for name, want := range map[string]bool{
+		"example.com":        true,
+		"files.example.com":  true,
+		"badexample.com":     false,
+		"example.com.evil":   false,
+		"pypi.org":           true,
+		"files.pypi.org":     true,
+		"proxy.golang.org":   true,
+		"github.com":         false,
+		"Files.Example.com.": true,
+	} {
+		if got := MatchDomain(allowed, name); got != want {
+			t.Fatalf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+}
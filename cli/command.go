@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"strings"
+	"time"
 
 	sec "github.com/seccomp/libseccomp-golang"
 )
@@ -46,6 +47,30 @@ func (s *SyscallDeniedAction) Set(value string) error {
 	return nil
 }
 
+// Backend implements flag.Value for the backend flag.
+type Backend string
+
+const (
+	PtraceBackend        Backend = "ptrace"
+	SeccompNotifyBackend Backend = "seccomp-notify"
+)
+
+func (b *Backend) String() string {
+	return string(*b)
+}
+
+func (b *Backend) Set(value string) error {
+	switch value {
+	case "ptrace":
+		*b = PtraceBackend
+	case "seccomp-notify":
+		*b = SeccompNotifyBackend
+	default:
+		return fmt.Errorf("invalid value for backend: %s.  Must be 'ptrace' or 'seccomp-notify'", value)
+	}
+	return nil
+}
+
 // Command groups the FlagSet and pointers to defined flags.
 type Command struct {
 	flagSet *flag.FlagSet
@@ -65,9 +90,12 @@ type Command struct {
 	AllowFileSystemPath *stringSlice
 	// Derived list (synonym) populated after Parse()
 	AllowFileSystemPathsList []string
+	// UseLandlock enforces filesystem permissions with Landlock if available
+	UseLandlock *bool
 
 	AllowNetworkClient             *bool
 	AllowNetworkServer             *bool
+	AllowNetworkLoopback           *bool
 	AllowNetworkLocalSockets       *bool
 	AllowProcessManagement         *bool
 	AllowNetworking                *bool
@@ -79,17 +107,44 @@ type Command struct {
 	AllowProcessCommunication      *bool
 	AllowProcessSynchronization    *bool
 	AllowMisc                      *bool
+	AllowIoUring                   *bool
 
 	EnforceOnStartup      *bool
 	AllowImplicitCommands *bool
+	AllowForeignAbi       *bool
+
+	AllowRawSockets *bool
+	// AllowNetlinkProtocol supports specifying the flag multiple times.
+	AllowNetlinkProtocol *stringSlice
+	AllowFdPassing       *bool
+	// AllowFdPassingPath supports specifying the flag multiple times, like
+	// AllowFileSystemPath.
+	AllowFdPassingPath *stringSlice
+
+	// AllowDomain supports specifying the flag multiple times.
+	AllowDomain      *stringSlice
+	LogDeniedDomains *bool
+	// AllowHost supports specifying the flag multiple times.
+	AllowHost   *stringSlice
+	EgressProxy *string
 
-	Action SyscallDeniedAction
+	NetworkAuditLog *string
+
+	// Hardening
+	DenyWriteExecute    *bool
+	AnonymousExecWindow *time.Duration
+
+	Action  SyscallDeniedAction
+	Backend Backend
+
+	// Pid is the process to attach to in attach mode
+	Pid *int
 }
 
 // NewCommand constructs the CLI FlagSet and returns a Command with pointers to all flags.
 func NewCommand() *Command {
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
-	c := &Command{flagSet: fs}
+	c := &Command{flagSet: fs, Backend: PtraceBackend}
 
 	// Triggers & verbosity
 	c.TriggerEnforceOnLogMatch = fs.String("trigger-enforce-on-log-match", "", "Enable enforcement when trace output contains this string (use with -enforce-on-startup=false)")
@@ -104,10 +159,29 @@ func NewCommand() *Command {
 	var allowFileSystemPaths stringSlice
 	fs.Var(&allowFileSystemPaths, "allow-file-system-path", "Allow filesystem path (repeatable); example: --allow-file-system-path=/etc --allow-file-system-path=/var")
 	c.AllowFileSystemPath = &allowFileSystemPaths // will be populated during Parse()
+	c.UseLandlock = fs.Bool("use-landlock", true, "Additionally enforce filesystem permissions and paths with a Landlock ruleset if the kernel supports it")
 
 	c.AllowNetworkClient = fs.Bool("allow-network-client", false, "Allow outbound network connections (socket/connect/send/recv)")
 	c.AllowNetworkServer = fs.Bool("allow-network-server", false, "Allow listening sockets and incoming connections (socket/bind/listen/accept)")
+	c.AllowNetworkLoopback = fs.Bool("allow-network-loopback", false, "Allow IPv4/IPv6 networking restricted to loopback addresses (connect/send to and bind/listen on 127.0.0.1, ::1), and the file descriptor operations of network programs (dup, fcntl, poll/epoll, pipe, eventfd)")
 	c.AllowNetworkLocalSockets = fs.Bool("allow-network-local-sockets", false, "Allow local-only sockets (AF_UNIX, AF_NETLINK) for client use")
+	c.AllowRawSockets = fs.Bool("allow-raw-sockets", false, "Allow raw IP sockets (SOCK_RAW) and packet sockets (AF_PACKET), in addition to --allow-network-client or --allow-network-server")
+	var allowNetlinkProtocols stringSlice
+	fs.Var(&allowNetlinkProtocols, "allow-netlink-protocol", "Allow a netlink protocol besides NETLINK_ROUTE (repeatable, needs --allow-network-local-sockets); example: --allow-netlink-protocol=generic")
+	c.AllowNetlinkProtocol = &allowNetlinkProtocols
+	c.AllowFdPassing = fs.Bool("allow-fd-passing", false, "Allow passing file descriptors over Unix sockets (SCM_RIGHTS)")
+	var allowFdPassingPaths stringSlice
+	fs.Var(&allowFdPassingPaths, "allow-fd-passing-path", "Allow passing file descriptors only over Unix sockets at this path (repeatable, implies --allow-fd-passing); example: --allow-fd-passing-path=/run/app.sock")
+	c.AllowFdPassingPath = &allowFdPassingPaths
+	var allowDomains stringSlice
+	fs.Var(&allowDomains, "allow-domain", "Allow DNS queries only for this domain and its subdomains (repeatable); example: --allow-domain=example.com")
+	c.AllowDomain = &allowDomains
+	var allowHosts stringSlice
+	fs.Var(&allowHosts, "allow-host", "Allow TCP connections only to this host and its subdomains, as named by the TLS server name or HTTP Host header (repeatable, needs --backend=ptrace); example: --allow-host=api.example.com")
+	c.AllowHost = &allowHosts
+	c.EgressProxy = fs.String("egress-proxy", "", "Start an HTTP CONNECT proxy at this address for the tracee and only allow it to connect to the proxy; the proxy enforces --allow-host, which is required (implies --allow-network-client, including its file descriptor operations: dup, fcntl, poll/epoll, pipe, eventfd); example: --egress-proxy=127.0.0.1:3128")
+	c.NetworkAuditLog = fs.String("network-audit-log", "", "Append a JSON line per connect, bind, accept, first send to each destination, DNS query and host of the tracee to this file, with its decision (needs --backend=ptrace); example: --network-audit-log=/var/log/gatekeeper-network.jsonl")
+	c.LogDeniedDomains = fs.Bool("log-denied-domains", false, "Log DNS queries outside --allow-domain instead of denying them")
 	c.AllowProcessManagement = fs.Bool("allow-process-management", false, "Allow process/thread creation and lifecycle control (exec/fork/clone/wait)")
 	c.AllowNetworking = fs.Bool("allow-networking", false, "Allow both client and server networking capabilities")
 	c.AllowMemoryManagement = fs.Bool("allow-memory-management", false, "Allow memory mapping and related syscalls (mmap/mprotect/mremap/brk)")
@@ -118,11 +192,19 @@ func NewCommand() *Command {
 	c.AllowProcessCommunication = fs.Bool("allow-process-communication", false, "Allow IPC mechanisms (SysV shared memory, semaphores, message queues, POSIX mqueue)")
 	c.AllowProcessSynchronization = fs.Bool("allow-process-synchronization", false, "Allow synchronization primitives (futex/flock/robust list)")
 	c.AllowMisc = fs.Bool("allow-misc", false, "Allow miscellaneous syscalls (includes ioctl, splice, vmsplice).")
+	c.AllowIoUring = fs.Bool("allow-io-uring", false, "Allow io_uring rings. Operations submitted through a ring (open, read, write, connect, ...) are NOT checked.")
 	c.EnforceOnStartup = fs.Bool("enforce-on-startup", true, "Start with enforcement enabled on startup (default)")
 	c.AllowImplicitCommands = fs.Bool("allow-implicit-commands", true, "Enable baseline implicit permissions; allow additional commands by default")
+	c.AllowForeignAbi = fs.Bool("allow-foreign-abi", false, "Allow syscalls of foreign ABIs, e.g. i386 (int 0x80) and x32 syscalls on x86-64")
+
+	// Hardening
+	c.DenyWriteExecute = fs.Bool("deny-write-execute", false, "Deny writable and executable memory, executable anonymous memory after --anonymous-exec-window and executing memfds")
+	c.AnonymousExecWindow = fs.Duration("anonymous-exec-window", 0, "Time after startup during which anonymous memory may still be made executable with --deny-write-execute, e.g. 5s")
 
 	// Custom action flag
 	fs.Var(&c.Action, "on-syscall-denied", "Action when a syscall is denied: 'kill' (SIGKILL) or 'error' (simulate EPERM via SIGSYS)")
+	fs.Var(&c.Backend, "backend", "Mechanism used to intercept syscalls: 'ptrace' (default) or 'seccomp-notify' (seccomp user notifications, tracee can still be debugged)")
+	c.Pid = fs.Int("pid", 0, "PID of the running process to gatekeep in attach mode")
 
 	return c
 }
@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"io"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParseAllowFileSystemPaths(t *testing.T) {
@@ -16,3 +18,119 @@ func TestParseAllowFileSystemPaths(t *testing.T) {
 		t.Fatalf("expected %v got %v", exp, c.AllowFileSystemPathsList)
 	}
 }
+
+func TestParseBackend(t *testing.T) {
+	c := NewCommand()
+	if c.Backend != PtraceBackend {
+		t.Fatalf("expected default backend %s got %s", PtraceBackend, c.Backend)
+	}
+	if err := c.Parse([]string{"--backend=seccomp-notify"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if c.Backend != SeccompNotifyBackend {
+		t.Fatalf("expected backend %s got %s", SeccompNotifyBackend, c.Backend)
+	}
+}
+
+func TestParseInvalidBackend(t *testing.T) {
+	c := NewCommand()
+	c.FlagSet().SetOutput(io.Discard)
+	if err := c.Parse([]string{"--backend=ebpf"}); err == nil {
+		t.Fatalf("expected Parse to fail for invalid backend")
+	}
+}
+
+func TestParsePid(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--pid=42"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *c.Pid != 42 {
+		t.Fatalf("expected pid 42 got %d", *c.Pid)
+	}
+	if len(c.Args()) != 0 {
+		t.Fatalf("expected no trailing args got %v", c.Args())
+	}
+}
+
+func TestParseHardening(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--deny-write-execute", "--anonymous-exec-window=5s"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !*c.DenyWriteExecute {
+		t.Fatalf("expected write-execute hardening to be enabled")
+	}
+	if *c.AnonymousExecWindow != 5*time.Second {
+		t.Fatalf("expected anonymous exec window 5s got %s", *c.AnonymousExecWindow)
+	}
+}
+
+func TestParseSocketGating(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--allow-raw-sockets", "--allow-netlink-protocol=generic", "--allow-netlink-protocol=audit"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !*c.AllowRawSockets {
+		t.Fatalf("expected raw sockets to be allowed")
+	}
+	exp := []string{"generic", "audit"}
+	if !reflect.DeepEqual([]string(*c.AllowNetlinkProtocol), exp) {
+		t.Fatalf("expected %v got %v", exp, *c.AllowNetlinkProtocol)
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--allow-domain=example.com", "--allow-domain=pypi.org", "--log-denied-domains"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	exp := []string{"example.com", "pypi.org"}
+	if !reflect.DeepEqual([]string(*c.AllowDomain), exp) {
+		t.Fatalf("expected %v got %v", exp, *c.AllowDomain)
+	}
+	if !*c.LogDeniedDomains {
+		t.Fatalf("expected denied domains to be logged")
+	}
+}
+
+func TestParseAllowHost(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--allow-host=api.example.com", "--allow-host=pypi.org"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	exp := []string{"api.example.com", "pypi.org"}
+	if !reflect.DeepEqual([]string(*c.AllowHost), exp) {
+		t.Fatalf("expected %v got %v", exp, *c.AllowHost)
+	}
+}
+
+func TestParseEgressProxy(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--egress-proxy=127.0.0.1:3128", "--allow-host=example.com"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *c.EgressProxy != "127.0.0.1:3128" {
+		t.Fatalf("expected egress proxy 127.0.0.1:3128 got %s", *c.EgressProxy)
+	}
+}
+
+func TestParseAllowNetworkLoopback(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--allow-network-loopback"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !*c.AllowNetworkLoopback || *c.AllowNetworkClient {
+		t.Fatalf("expected only loopback networking to be allowed")
+	}
+}
+
+func TestParseNetworkAuditLog(t *testing.T) {
+	c := NewCommand()
+	if err := c.Parse([]string{"--network-audit-log=/tmp/network.jsonl"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *c.NetworkAuditLog != "/tmp/network.jsonl" {
+		t.Fatalf("expected audit log /tmp/network.jsonl got %s", *c.NetworkAuditLog)
+	}
+}
@@ -0,0 +1,182 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+// Package gatekeeper embeds the syscall gatekeeper in Go programs.
+//
+// Each Gatekeeper owns its policy, enforcement state and syscall statistics,
+// so several of them can supervise different processes in one program.
+//
+//	gk, err := gatekeeper.New(gatekeeper.Options{Policy: policy})
+//	if err != nil {
+//		return err
+//	}
+//	if err := gk.Start(ctx, exec.Command("cat", "/etc/hostname")); err != nil {
+//		return err
+//	}
+//	result, err := gk.Wait()
+//
+// Programs using the seccomp-notify backend must call RunHelper at the start
+// of their main function, because the backend re-executes the program to
+// install the seccomp filter in the supervised process.
+package gatekeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot"
+)
+
+// Options configures a Gatekeeper.
+type Options struct {
+	// Policy is the configuration to enforce. It is copied by New,
+	// including its slices and maps, so changing it afterwards has no
+	// effect on the Gatekeeper.
+	// SyscallsAllowMap is created from SyscallsAllowList if it is nil and
+	// Backend defaults to ptrace.
+	Policy runtime.Config
+
+	// Callbacks are called for every process event of the supervised
+	// process tree.
+	Callbacks []uroot.EventCallback
+}
+
+// Result describes how a supervised process terminated.
+type Result struct {
+	// ExitCode is the exit code of the process or of the gatekeeper if it
+	// failed to supervise the process.
+	ExitCode int
+	// Signal is the name of the signal that terminated the process, if any.
+	Signal string
+	// SyscallsBeforeEnforce counts the syscalls made before the policy was
+	// enforced.
+	SyscallsBeforeEnforce map[string]int64
+	// SyscallsAfterEnforce counts the syscalls made after the policy was
+	// enforced.
+	SyscallsAfterEnforce map[string]int64
+}
+
+// Gatekeeper supervises a single process and its children.
+type Gatekeeper struct {
+	session   *uroot.Session
+	callbacks []uroot.EventCallback
+
+	mu     sync.Mutex
+	exited context.Context
+}
+
+// ErrNotStarted is returned by Wait if Start was not called successfully.
+var ErrNotStarted = errors.New("gatekeeper was not started")
+
+// ErrAlreadyStarted is returned by Start if the gatekeeper already
+// supervises a process.
+var ErrAlreadyStarted = errors.New("gatekeeper was already started")
+
+// New creates a Gatekeeper enforcing opts.Policy.
+func New(opts Options) (*Gatekeeper, error) {
+	policy := opts.Policy.Clone()
+	switch policy.Backend {
+	case "":
+		policy.Backend = runtime.BACKEND_PTRACE
+	case runtime.BACKEND_PTRACE, runtime.BACKEND_SECCOMP_NOTIFY:
+	default:
+		return nil, fmt.Errorf("invalid backend %q", policy.Backend)
+	}
+	if policy.SyscallsAllowMap == nil {
+		policy.SyscallsAllowMap = runtime.CreateSyscallAllowMap(policy.SyscallsAllowList)
+	}
+
+	return &Gatekeeper{
+		session:   uroot.NewSession(&policy),
+		callbacks: opts.Callbacks,
+	}, nil
+}
+
+// Start starts cmd and supervises it until it exits. Cancelling ctx stops
+// waiting for the process, see Wait.
+func (g *Gatekeeper) Start(ctx context.Context, cmd *exec.Cmd) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.exited != nil {
+		return ErrAlreadyStarted
+	}
+
+	exited, err := g.session.Start(ctx, cmd, g.callbacks...)
+	if err != nil {
+		return err
+	}
+	g.exited = exited
+	return nil
+}
+
+// Attach supervises the already running process pid and the children it
+// creates from now on. Cancelling ctx detaches from the process, which keeps
+// running. Attaching requires the ptrace backend.
+func (g *Gatekeeper) Attach(ctx context.Context, pid int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.exited != nil {
+		return ErrAlreadyStarted
+	}
+
+	exited, err := g.session.Attach(ctx, pid, g.callbacks...)
+	if err != nil {
+		return err
+	}
+	g.exited = exited
+	return nil
+}
+
+// Wait waits until the supervised process exited or the context passed to
+// Start was cancelled. In the latter case the context's error is returned.
+// Processes supervised with Attach are detached when the context is
+// cancelled and Wait returns a zero ExitCode.
+func (g *Gatekeeper) Wait() (Result, error) {
+	g.mu.Lock()
+	exited := g.exited
+	g.mu.Unlock()
+	if exited == nil {
+		return Result{}, ErrNotStarted
+	}
+
+	<-exited.Done()
+	result := Result{
+		SyscallsBeforeEnforce: g.session.SyscallsCollectedBeforeEnforce(),
+		SyscallsAfterEnforce:  g.session.SyscallsCollectedAfterEnforce(),
+	}
+
+	e := &uroot.ExitEventError{}
+	if !errors.As(context.Cause(exited), &e) {
+		return result, context.Cause(exited)
+	}
+	result.ExitCode = e.ExitCode
+	result.Signal = e.Signal
+	return result, nil
+}
+
+// Enforce starts enforcing the policy if Policy.EnforceOnStartup was not set.
+func (g *Gatekeeper) Enforce() {
+	g.session.Enforce()
+}
+
+// IsEnforced returns whether the policy is enforced.
+func (g *Gatekeeper) IsEnforced() bool {
+	return g.session.IsEnforced()
+}
+
+// RunHelper runs the seccomp-notify helper and does not return if the
+// program was re-executed as such. Otherwise it returns immediately.
+func RunHelper() {
+	if len(os.Args) < 2 || os.Args[1] != uroot.SeccompExecCommand {
+		return
+	}
+	if err := uroot.SeccompExec(os.Args[2:]); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(101)
+	}
+	os.Exit(0)
+}
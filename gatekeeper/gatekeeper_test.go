@@ -0,0 +1,277 @@
+//go:build (linux && arm64) || (linux && amd64) || (linux && riscv64)
+
+package gatekeeper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/cuandari/lib/app/runtime"
+	"github.com/cuandari/lib/app/uroot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the seccomp-notify helper when the test binary is
+// re-executed as such.
+func TestMain(m *testing.M) {
+	RunHelper()
+	os.Exit(m.Run())
+}
+
+func TestNewDefaultsBackendAndAllowMap(t *testing.T) {
+	gk, err := New(Options{Policy: runtime.Config{
+		SyscallConfig: runtime.SyscallConfig{SyscallsAllowList: []string{"read"}},
+	}})
+	require.NoError(t, err)
+
+	conf := gk.session.Config()
+	assert.Equal(t, runtime.BACKEND_PTRACE, conf.Backend)
+	assert.True(t, conf.SyscallsAllowMap["read"])
+	assert.False(t, conf.SyscallsAllowMap["write"])
+}
+
+func TestNewRejectsInvalidBackend(t *testing.T) {
+	_, err := New(Options{Policy: runtime.Config{
+		GatekeeperConfig: runtime.GatekeeperConfig{Backend: "ebpf"},
+	}})
+	assert.Error(t, err)
+}
+
+func TestNewCopiesPolicy(t *testing.T) {
+	policy := runtime.Config{
+		FsConfig:      runtime.FsConfig{FileSystemAllowedPaths: []string{"/tmp"}},
+		NetworkConfig: runtime.NetworkConfig{NetworkAllowedHosts: []string{"example.com"}},
+		SyscallConfig: runtime.SyscallConfig{SyscallsAllowMap: map[string]bool{"read": true}},
+	}
+	gk, err := New(Options{Policy: policy})
+	require.NoError(t, err)
+
+	policy.EnforceOnStartup = true
+	policy.FileSystemAllowedPaths[0] = "/"
+	policy.NetworkAllowedHosts[0] = "evil.test"
+	policy.SyscallsAllowMap["write"] = true
+	conf := gk.session.Config()
+	assert.False(t, conf.EnforceOnStartup)
+	assert.Equal(t, []string{"/tmp"}, conf.FileSystemAllowedPaths)
+	assert.Equal(t, []string{"example.com"}, conf.NetworkAllowedHosts)
+	assert.False(t, conf.SyscallsAllowMap["write"])
+}
+
+func TestInstancesAreIndependent(t *testing.T) {
+	enforced, err := New(Options{Policy: runtime.Config{
+		GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+	}})
+	require.NoError(t, err)
+	relaxed, err := New(Options{Policy: runtime.Config{}})
+	require.NoError(t, err)
+
+	assert.True(t, enforced.IsEnforced())
+	assert.False(t, relaxed.IsEnforced())
+
+	relaxed.Enforce()
+	assert.True(t, relaxed.IsEnforced())
+}
+
+func TestWaitBeforeStart(t *testing.T) {
+	gk, err := New(Options{})
+	require.NoError(t, err)
+
+	_, err = gk.Wait()
+	assert.ErrorIs(t, err, ErrNotStarted)
+}
+
+func TestStartAndWait(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	gk, err := New(Options{Policy: runtime.Config{
+		FsConfig:         runtime.FsConfig{FileSystemAllowRead: true},
+		GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+	}})
+	require.NoError(t, err)
+
+	require.NoError(t, gk.Start(context.Background(), exec.Command("sh", "-c", "exit 3")))
+	assert.ErrorIs(t, gk.Start(context.Background(), exec.Command("true")), ErrAlreadyStarted)
+
+	result, err := gk.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Empty(t, result.SyscallsBeforeEnforce)
+	assert.NotEmpty(t, result.SyscallsAfterEnforce)
+}
+
+func TestSeccompNotifyKeepsExtraFiles(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	gk, err := New(Options{Policy: runtime.Config{
+		FsConfig:         runtime.FsConfig{FileSystemAllowRead: true},
+		GatekeeperConfig: runtime.GatekeeperConfig{Backend: runtime.BACKEND_SECCOMP_NOTIFY, EnforceOnStartup: true},
+	}})
+	require.NoError(t, err)
+
+	// The file of the caller is fd 3 of the supervised process.
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	cmd := exec.Command("sh", "-c", "exec 3>&-; exit 3")
+	cmd.ExtraFiles = []*os.File{w}
+	require.NoError(t, gk.Start(context.Background(), cmd))
+
+	result, err := gk.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+
+	// The helper announced the listener on its own pipe.
+	w.Close()
+	written, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, written)
+}
+
+func TestConcurrentInstances(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// A child that is not gatekept must keep its exit status.
+	unrelated := exec.Command("sh", "-c", "sleep 0.2; exit 7")
+	require.NoError(t, unrelated.Start())
+
+	exitCodes := []int{4, 5, 6}
+	gatekeepers := make([]*Gatekeeper, len(exitCodes))
+	for i, code := range exitCodes {
+		gk, err := New(Options{Policy: runtime.Config{
+			FsConfig:         runtime.FsConfig{FileSystemAllowRead: true},
+			GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+		}})
+		require.NoError(t, err)
+		cmd := exec.Command("sh", "-c", fmt.Sprintf("sleep 0.1; exit %d", code))
+		require.NoError(t, gk.Start(context.Background(), cmd))
+		gatekeepers[i] = gk
+	}
+
+	for i, gk := range gatekeepers {
+		result, err := gk.Wait()
+		require.NoError(t, err)
+		assert.Equal(t, exitCodes[i], result.ExitCode)
+	}
+
+	err := unrelated.Wait()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 7, exitErr.ExitCode())
+}
+
+func TestAttachEnforcesPolicy(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	cmd := exec.Command("sh", "-c", "read line; cat /etc/hostname")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	gk, err := New(Options{Policy: runtime.Config{
+		GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, gk.Attach(context.Background(), cmd.Process.Pid))
+
+	_, err = stdin.Write([]byte("go\n"))
+	require.NoError(t, err)
+
+	result, err := gk.Wait()
+	require.NoError(t, err)
+	// cat is killed because reading files is not allowed
+	assert.Equal(t, 128+9, result.ExitCode)
+	assert.NotEmpty(t, result.SyscallsAfterEnforce)
+}
+
+func TestAttachDetachLeavesProcessRunning(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	cmd := exec.Command("sh", "-c", "read line; exit 5")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	gk, err := New(Options{Policy: runtime.Config{
+		GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+	}})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, gk.Attach(ctx, cmd.Process.Pid))
+
+	cancel()
+	result, err := gk.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+
+	_, err = stdin.Write([]byte("go\n"))
+	require.NoError(t, err)
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 5, exitErr.ExitCode())
+}
+
+func TestAttachRequiresPtraceBackend(t *testing.T) {
+	gk, err := New(Options{Policy: runtime.Config{
+		GatekeeperConfig: runtime.GatekeeperConfig{Backend: runtime.BACKEND_SECCOMP_NOTIFY},
+	}})
+	require.NoError(t, err)
+
+	assert.Error(t, gk.Attach(context.Background(), 1))
+}
+
+func TestSyscallExitCarriesEntry(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	var records []uroot.TraceRecord
+	gk, err := New(Options{
+		Policy: runtime.Config{
+			FsConfig:         runtime.FsConfig{FileSystemAllowRead: true},
+			GatekeeperConfig: runtime.GatekeeperConfig{EnforceOnStartup: true},
+		},
+		Callbacks: []uroot.EventCallback{func(_ uroot.Task, rec *uroot.TraceRecord) {
+			records = append(records, *rec)
+		}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, gk.Start(context.Background(), exec.Command("sh", "-c", "exit 0")))
+	_, err = gk.Wait()
+	require.NoError(t, err)
+
+	entries := make(map[int]*uroot.SyscallEvent)
+	exits := 0
+	for _, rec := range records {
+		switch rec.Event {
+		case uroot.SyscallEnter:
+			entries[rec.PID] = rec.Syscall
+		case uroot.SyscallExit:
+			enter, ok := entries[rec.PID]
+			require.True(t, ok, "exit without entry")
+			assert.Equal(t, enter.Sysno, rec.Syscall.Sysno)
+			assert.Equal(t, enter.Args, rec.Syscall.Args)
+			assert.Positive(t, rec.Syscall.Duration)
+			delete(entries, rec.PID)
+			exits++
+		}
+	}
+	assert.Positive(t, exits)
+}
@@ -5,13 +5,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/cuandari/lib/app/audit"
+	"github.com/cuandari/lib/app/egress"
 	"github.com/cuandari/lib/app/runtime"
 	"github.com/cuandari/lib/app/uroot"
+	"github.com/cuandari/lib/app/uroot/syscalls"
 	"github.com/cuandari/lib/cli"
 )
 
@@ -23,6 +27,16 @@ func exit(code int) {
 // CLI flag type and constants moved to cli package.
 
 func main() {
+	// The seccomp-notify backend re-executes the gatekeeper to install the
+	// filter in the tracee before the target binary is executed.
+	if len(os.Args) > 1 && os.Args[1] == uroot.SeccompExecCommand {
+		if err := uroot.SeccompExec(os.Args[2:]); err != nil {
+			fmt.Println(err.Error())
+			exit(101)
+		}
+		return
+	}
+
 	mainCtx, cancel := context.WithCancel(context.Background())
 	println(fmt.Sprintf("gatekeeper started with %#+v", os.Args))
 
@@ -31,12 +45,29 @@ func main() {
 }
 
 func startTracee(c context.Context) context.Context {
-	args := configureAndParseArgs()
+	args, attachPid := configureAndParseArgs()
+	session := uroot.NewSession(runtime.Get())
+	networkAudit := openNetworkAudit(runtime.Get())
+	session.SetNetworkAudit(networkAudit)
+	if runtime.Get().NetworkEgressProxy != "" {
+		startEgressProxy(runtime.Get(), networkAudit)
+	}
+
+	if attachPid > 0 {
+		println(fmt.Sprintf("attaching to pid %d", attachPid))
+		exitContext, err := session.Attach(c, attachPid, uroot.PrintTraces(os.Stdout))
+		if err != nil {
+			fmt.Println(err.Error())
+			exit(2)
+		}
+		return exitContext
+	}
+
 	program := args[0]
 	programArgs := args[1:]
 	println(fmt.Sprintf("starting %s with args %#+v", program, programArgs))
 
-	_, exitContext, err := uroot.Exec(c, program, programArgs)
+	_, exitContext, err := session.Exec(c, program, programArgs)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
@@ -44,7 +75,39 @@ func startTracee(c context.Context) context.Context {
 	return exitContext
 }
 
-func configureAndParseArgs() []string {
+// startEgressProxy starts the proxy the network traffic of the tracee is
+// routed through.
+func startEgressProxy(conf *runtime.Config, networkAudit *audit.Log) {
+	proxy, err := egress.Listen(conf.NetworkEgressProxy, conf.NetworkAllowedHosts)
+	if err != nil {
+		fmt.Println(err.Error())
+		exit(2)
+	}
+	proxy.Log = os.Stdout
+	proxy.Audit = networkAudit
+	println(fmt.Sprintf("egress proxy listening on %s", proxy.Addr()))
+	go func() {
+		if err := proxy.Serve(); err != nil {
+			fmt.Printf("egress proxy stopped: %s\n", err.Error())
+		}
+	}()
+}
+
+// openNetworkAudit opens the network audit log of the tracee, or returns
+// nil if it is not configured.
+func openNetworkAudit(conf *runtime.Config) *audit.Log {
+	if conf.NetworkAuditLog == "" {
+		return nil
+	}
+	f, err := os.OpenFile(conf.NetworkAuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		fmt.Printf("Unable to open the network audit log: %s\n", err.Error())
+		exit(2)
+	}
+	return audit.New(f)
+}
+
+func configureAndParseArgs() ([]string, int) {
 	conf := runtime.Get()
 
 	c := cli.NewCommand()
@@ -107,12 +170,66 @@ func configureAndParseArgs() []string {
 		conf.NetworkAllowServer = true
 	}
 
+	if *c.AllowNetworkLoopback {
+		// Addresses are restricted to loopback by the gatekeeper
+		allowList.AllowNetworking()
+		allowList.AllowAllFileDescriptors()
+		conf.NetworkAllowLoopback = true
+	}
+
 	if *c.AllowNetworkLocalSockets {
 		allowList.AllowLocalSockets()
 		// allowList.AllowAllFileDescriptors()
 		conf.LocalSocketsAllow = true
 	}
 
+	conf.NetworkAllowRawSockets = *c.AllowRawSockets
+	for _, name := range *c.AllowNetlinkProtocol {
+		if _, ok := syscalls.NetlinkProtocol(name); !ok {
+			fmt.Printf("Error: Unknown netlink protocol %s.\n", name)
+			c.Usage()
+			exit(100)
+		}
+	}
+	conf.LocalSocketsNetlinkProtocols = *c.AllowNetlinkProtocol
+
+	if *c.AllowFdPassing || len(*c.AllowFdPassingPath) > 0 {
+		conf.LocalSocketsAllowFdPassing = true
+		conf.LocalSocketsFdPassingPaths = *c.AllowFdPassingPath
+	}
+
+	conf.NetworkAllowedDomains = *c.AllowDomain
+	conf.NetworkLogDeniedDomains = *c.LogDeniedDomains
+	conf.NetworkAllowedHosts = *c.AllowHost
+	conf.NetworkAuditLog = *c.NetworkAuditLog
+	if *c.EgressProxy != "" {
+		proxy, err := netip.ParseAddrPort(*c.EgressProxy)
+		if err != nil {
+			fmt.Printf("Error: The egress proxy address %s is not an IP address and port.\n", *c.EgressProxy)
+			c.Usage()
+			exit(100)
+		}
+		// The tracee may only reach the exact proxy address, which an
+		// unspecified address like 0.0.0.0 never is.
+		if proxy.Addr().IsUnspecified() {
+			fmt.Printf("Error: The egress proxy address %s must not be unspecified, e.g. use 127.0.0.1.\n", *c.EgressProxy)
+			c.Usage()
+			exit(100)
+		}
+		if len(conf.NetworkAllowedHosts) == 0 {
+			fmt.Println("Error: --egress-proxy requires at least one --allow-host to open tunnels to.")
+			c.Usage()
+			exit(100)
+		}
+		// The tracee may only connect to the proxy. Like
+		// --allow-network-client, the fd operations clients need, e.g.
+		// poll and fcntl, are allowed, too.
+		allowList.AllowNetworkClient()
+		allowList.AllowAllFileDescriptors()
+		conf.NetworkAllowClient = true
+		conf.NetworkEgressProxy = *c.EgressProxy
+	}
+
 	if *c.AllowNetworking {
 		allowList.AllowNetworking()
 	}
@@ -149,6 +266,10 @@ func configureAndParseArgs() []string {
 		allowList.AllowMisc()
 	}
 
+	if *c.AllowIoUring {
+		allowList.AllowIoUring()
+	}
+
 	// Append dynamically allowed syscalls collected from CLI
 	if len(dynamicSyscalls) > 0 {
 		allowList.Syscalls = append(allowList.Syscalls, dynamicSyscalls...)
@@ -171,6 +292,7 @@ func configureAndParseArgs() []string {
 	if len(c.AllowFileSystemPathsList) > 0 {
 		runtime.Get().FileSystemAllowedPaths = c.AllowFileSystemPathsList
 	}
+	conf.FileSystemUseLandlock = *c.UseLandlock
 
 	if !*c.EnforceOnStartup {
 		if *c.TriggerEnforceOnLogMatch == "" && *c.TriggerEnforceOnSignal == "" {
@@ -203,17 +325,56 @@ func configureAndParseArgs() []string {
 		conf.SyscallsDenyTargetIfNotAllowed = false
 	}
 
+	conf.SyscallsAllowForeignAbi = *c.AllowForeignAbi
+	conf.MemoryDenyWriteExecute = *c.DenyWriteExecute
+	conf.MemoryAnonymousExecWindow = *c.AnonymousExecWindow
+
+	if c.Backend == cli.SeccompNotifyBackend {
+		conf.Backend = runtime.BACKEND_SECCOMP_NOTIFY
+	} else {
+		conf.Backend = runtime.BACKEND_PTRACE
+	}
+	if len(conf.NetworkAllowedHosts) > 0 && conf.NetworkEgressProxy == "" && conf.Backend != runtime.BACKEND_PTRACE {
+		fmt.Println("Error: --allow-host requires --backend=ptrace, which tracks the first data sent on a connection, or --egress-proxy.")
+		c.Usage()
+		exit(100)
+	}
+	if conf.NetworkAuditLog != "" && conf.Backend != runtime.BACKEND_PTRACE {
+		fmt.Println("Error: --network-audit-log requires --backend=ptrace, which sees the connections accepted by the tracee.")
+		c.Usage()
+		exit(100)
+	}
+
 	switch mode {
 	case "trace":
 		conf.ExecutionMode = runtime.EXECUTION_MODE_TRACE
 	case "run":
 		conf.ExecutionMode = runtime.EXECUTION_MODE_RUN
+	case "attach":
+		conf.ExecutionMode = runtime.EXECUTION_MODE_RUN
+		if *c.Pid <= 0 {
+			fmt.Println("Error: Please specify the process to attach to with --pid.")
+			c.Usage()
+			exit(100)
+		}
+		if !conf.EnforceOnStartup || conf.Backend != runtime.BACKEND_PTRACE {
+			fmt.Println("Error: Attaching to a process requires --backend=ptrace and enforces the policy immediately.")
+			c.Usage()
+			exit(100)
+		}
+		return nil, *c.Pid
 	default:
 		c.Usage()
 		exit(100)
 	}
 
-	return c.Args()
+	if len(c.Args()) < 1 {
+		fmt.Println("Error: You did not provide the binary to execute.")
+		c.Usage()
+		exit(100)
+	}
+
+	return c.Args(), 0
 }
 
 func waitForShutdown(cancel context.CancelFunc, tracee context.Context) {